@@ -0,0 +1,159 @@
+package binary
+
+import (
+	"errors"
+	"io"
+)
+
+var ErrInvalidExpGolomb = errors.New("Exp-Golomb code out of range")
+
+// BitReader reads bits from a byte slice, most significant bit first.
+type BitReader struct {
+	buf []byte
+	pos int // Position of the next bit to be read
+}
+
+func NewBitReader(b []byte) *BitReader {
+	return &BitReader{buf: b}
+}
+
+// ReadBits reads n (<= 32) bits as an unsigned integer.
+func (r *BitReader) ReadBits(n int) (uint32, error) {
+	if n < 0 || n > 32 {
+		return 0, io.ErrShortBuffer
+	}
+
+	v, err := r.ReadBits64(n)
+	return uint32(v), err
+}
+
+// ReadBits64 reads n (<= 64) bits as an unsigned integer.
+func (r *BitReader) ReadBits64(n int) (uint64, error) {
+	if n < 0 || n > 64 {
+		return 0, io.ErrShortBuffer
+	}
+
+	if r.pos+n > len(r.buf)*8 {
+		return 0, io.ErrUnexpectedEOF
+	}
+
+	var v uint64
+
+	for i := 0; i < n; i++ {
+		bit := (r.buf[r.pos>>3] >> (7 - uint(r.pos&7))) & 1
+		v = v<<1 | uint64(bit)
+		r.pos++
+	}
+
+	return v, nil
+}
+
+// ReadFlag reads 1 bit as a boolean.
+func (r *BitReader) ReadFlag() (bool, error) {
+	bit, err := r.ReadBits(1)
+	return bit == 1, err
+}
+
+// BitsLeft returns the number of bits not read yet.
+func (r *BitReader) BitsLeft() int {
+	return len(r.buf)*8 - r.pos
+}
+
+// ByteAligned reports whether the next bit to be read is the first bit of a byte.
+func (r *BitReader) ByteAligned() bool {
+	return r.pos&7 == 0
+}
+
+// ByteAlign skips the remaining bits of current byte.
+func (r *BitReader) ByteAlign() {
+	r.pos = (r.pos + 7) &^ 7
+}
+
+// Skip skips n bits.
+func (r *BitReader) Skip(n int) error {
+	if n < 0 || r.pos+n > len(r.buf)*8 {
+		return io.ErrUnexpectedEOF
+	}
+
+	r.pos += n
+	return nil
+}
+
+// ReadUE reads an unsigned Exp-Golomb code ue(v), which is at most 2^32 - 2.
+func (r *BitReader) ReadUE() (uint32, error) {
+	v, err := r.readExpGolomb(31)
+	return uint32(v), err
+}
+
+// ReadSE reads a signed Exp-Golomb code se(v).
+func (r *BitReader) ReadSE() (int32, error) {
+	// The code of math.MinInt32 is 2^32, which has 32 leading zeros
+	v, err := r.readExpGolomb(32)
+	if err != nil {
+		return 0, err
+	}
+
+	// Codes beyond int32 range
+	if v > 1<<32 || v == 1<<32-1 {
+		return 0, ErrInvalidExpGolomb
+	}
+
+	// 1, 2, 3, 4, ... are mapped to 1, -1, 2, -2, ...
+	if v&1 == 1 {
+		return int32((v + 1) / 2), nil
+	}
+
+	return int32(-int64(v / 2)), nil
+}
+
+// readExpGolomb reads an Exp-Golomb code of at most maxLeadingZeros leading zero bits.
+func (r *BitReader) readExpGolomb(maxLeadingZeros int) (uint64, error) {
+	leadingZeros := 0
+
+	for {
+		bit, err := r.ReadBits(1)
+		if err != nil {
+			return 0, err
+		}
+
+		if bit == 1 {
+			break
+		}
+
+		leadingZeros++
+		if leadingZeros > maxLeadingZeros {
+			return 0, ErrInvalidExpGolomb
+		}
+	}
+
+	suffix, err := r.ReadBits64(leadingZeros)
+	if err != nil {
+		return 0, err
+	}
+
+	return (1<<uint(leadingZeros) - 1) + suffix, nil
+}
+
+// RemoveEmulationPrevention removes emulation prevention bytes (0x03 of 0x000003)
+// from NAL unit payload, returns the raw byte sequence payload (RBSP).
+func RemoveEmulationPrevention(b []byte) []byte {
+	rbsp := make([]byte, 0, len(b))
+	zeros := 0
+
+	for _, c := range b {
+		if zeros >= 2 && c == 0x03 {
+			zeros = 0
+			continue
+		}
+
+		if c == 0 {
+			zeros++
+		} else {
+			zeros = 0
+		}
+
+		rbsp = append(rbsp, c)
+	}
+
+	return rbsp
+}
@@ -0,0 +1,254 @@
+package binary
+
+import (
+	"bytes"
+	"io"
+	"math"
+	"testing"
+)
+
+func TestReadBits(t *testing.T) {
+	buf := []byte{0xA5, 0xFF, 0x00, 0x3C, 0x12, 0x34, 0x56, 0x78, 0x9A}
+
+	tests := []struct {
+		name string
+		skip int
+		n    int
+		want uint64
+	}{
+		{"0 bits", 0, 0, 0},
+		{"1 bit", 0, 1, 1},
+		{"1 bit unaligned", 1, 1, 0},
+		{"4 bits", 0, 4, 0xA},
+		{"across byte boundary", 4, 8, 0x5F},
+		{"across three bytes", 6, 12, 0x7FC},
+		{"32 bits", 0, 32, 0xA5FF003C},
+		{"32 bits unaligned", 4, 32, 0x5FF003C1},
+		{"64 bits", 0, 64, 0xA5FF003C12345678},
+		{"64 bits unaligned", 8, 64, 0xFF003C123456789A},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			r := NewBitReader(buf)
+			if err := r.Skip(test.skip); err != nil {
+				t.Fatalf("Skip(%d) = %v", test.skip, err)
+			}
+
+			got, err := r.ReadBits64(test.n)
+			if err != nil || got != test.want {
+				t.Errorf("ReadBits64(%d) = %#x, %v, want %#x", test.n, got, err, test.want)
+			}
+
+			if left := r.BitsLeft(); left != len(buf)*8-test.skip-test.n {
+				t.Errorf("BitsLeft() = %d, want %d", left, len(buf)*8-test.skip-test.n)
+			}
+
+			if test.n > 32 {
+				return
+			}
+
+			r = NewBitReader(buf)
+			r.Skip(test.skip)
+
+			got32, err := r.ReadBits(test.n)
+			if err != nil || uint64(got32) != test.want {
+				t.Errorf("ReadBits(%d) = %#x, %v, want %#x", test.n, got32, err, test.want)
+			}
+		})
+	}
+}
+
+func TestReadBitsEOF(t *testing.T) {
+	tests := []struct {
+		name string
+		buf  []byte
+		skip int
+		n    int
+	}{
+		{"empty", nil, 0, 1},
+		{"1 bit past end", []byte{0xFF}, 0, 9},
+		{"unaligned past end", []byte{0xFF, 0xFF}, 9, 8},
+		{"32 bits of 3 bytes", []byte{1, 2, 3}, 0, 32},
+		{"64 bits of 7 bytes", []byte{1, 2, 3, 4, 5, 6, 7}, 0, 64},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			r := NewBitReader(test.buf)
+			r.Skip(test.skip)
+
+			if _, err := r.ReadBits64(test.n); err != io.ErrUnexpectedEOF {
+				t.Errorf("ReadBits64(%d) error = %v, want %v", test.n, err, io.ErrUnexpectedEOF)
+			}
+
+			// Failed read does not consume bits
+			if left := r.BitsLeft(); left != len(test.buf)*8-test.skip {
+				t.Errorf("BitsLeft() = %d, want %d", left, len(test.buf)*8-test.skip)
+			}
+		})
+	}
+
+	r := NewBitReader([]byte{0xFF})
+	if err := r.Skip(9); err != io.ErrUnexpectedEOF {
+		t.Errorf("Skip(9) error = %v, want %v", err, io.ErrUnexpectedEOF)
+	}
+
+	if _, err := r.ReadBits(33); err == nil {
+		t.Error("ReadBits(33) succeeded, want error")
+	}
+
+	if _, err := r.ReadBits64(65); err == nil {
+		t.Error("ReadBits64(65) succeeded, want error")
+	}
+}
+
+func TestByteAlign(t *testing.T) {
+	r := NewBitReader([]byte{0xFF, 0x80})
+
+	if !r.ByteAligned() {
+		t.Error("ByteAligned() = false at start")
+	}
+
+	r.Skip(3)
+	r.ByteAlign()
+
+	if !r.ByteAligned() || r.BitsLeft() != 8 {
+		t.Errorf("after ByteAlign() BitsLeft() = %d, want 8", r.BitsLeft())
+	}
+
+	flag, err := r.ReadFlag()
+	if err != nil || !flag {
+		t.Errorf("ReadFlag() = %v, %v, want true", flag, err)
+	}
+}
+
+func TestReadUE(t *testing.T) {
+	tests := []struct {
+		buf  []byte
+		want uint32
+	}{
+		{[]byte{0x80}, 0},        // 1
+		{[]byte{0x40}, 1},        // 010
+		{[]byte{0x60}, 2},        // 011
+		{[]byte{0x20}, 3},        // 00100
+		{[]byte{0x38}, 6},        // 00111
+		{[]byte{0x08, 0x80}, 16}, // 000010001
+	}
+
+	for _, test := range tests {
+		got, err := NewBitReader(test.buf).ReadUE()
+		if err != nil || got != test.want {
+			t.Errorf("ReadUE(% x) = %d, %v, want %d", test.buf, got, err, test.want)
+		}
+	}
+}
+
+func TestReadUELimit(t *testing.T) {
+	// 32 leading zeros exceed the 31-leading-zero limit of ue(v)
+	w := NewBitWriter()
+	w.WriteBits(0, 32)
+	w.WriteBits(1, 1)
+	w.WriteBits(0, 32)
+
+	if _, err := NewBitReader(w.Bytes()).ReadUE(); err != ErrInvalidExpGolomb {
+		t.Errorf("ReadUE() error = %v, want %v", err, ErrInvalidExpGolomb)
+	}
+
+	// 31 leading zeros without suffix
+	w = NewBitWriter()
+	w.WriteBits(0, 31)
+	w.WriteBits(1, 1)
+
+	if _, err := NewBitReader(w.Bytes()).ReadUE(); err != io.ErrUnexpectedEOF {
+		t.Errorf("ReadUE() error = %v, want %v", err, io.ErrUnexpectedEOF)
+	}
+
+	// All zeros
+	if _, err := NewBitReader([]byte{0, 0}).ReadUE(); err != io.ErrUnexpectedEOF {
+		t.Errorf("ReadUE() error = %v, want %v", err, io.ErrUnexpectedEOF)
+	}
+}
+
+func TestUERoundTrip(t *testing.T) {
+	values := []uint32{0, 1, 2, 3, 7, 8, 254, 255, 256, 65535, 1 << 31, math.MaxUint32 - 1}
+
+	w := NewBitWriter()
+	for _, v := range values {
+		w.WriteUE(v)
+	}
+
+	r := NewBitReader(w.Bytes())
+	for _, want := range values {
+		got, err := r.ReadUE()
+		if err != nil || got != want {
+			t.Errorf("ReadUE() = %d, %v, want %d", got, err, want)
+		}
+	}
+
+	if r.BitsLeft() >= 8 {
+		t.Errorf("BitsLeft() = %d after reading all codes", r.BitsLeft())
+	}
+}
+
+func TestSERoundTrip(t *testing.T) {
+	values := []int32{0, 1, -1, 2, -2, 100, -100, math.MaxInt32, math.MinInt32 + 1, math.MinInt32}
+
+	for _, want := range values {
+		w := NewBitWriter()
+		w.WriteSE(want)
+
+		got, err := NewBitReader(w.Bytes()).ReadSE()
+		if err != nil || got != want {
+			t.Errorf("ReadSE(WriteSE(%d)) = %d, %v", want, got, err)
+		}
+	}
+}
+
+func TestSEMapping(t *testing.T) {
+	// se(v) 0, 1, -1, 2, -2 are coded as ue(v) 0, 1, 2, 3, 4
+	for i, v := range []int32{0, 1, -1, 2, -2} {
+		w := NewBitWriter()
+		w.WriteSE(v)
+
+		ue, err := NewBitReader(w.Bytes()).ReadUE()
+		if err != nil || ue != uint32(i) {
+			t.Errorf("WriteSE(%d) coded as ue(v) %d, %v, want %d", v, ue, err, i)
+		}
+	}
+}
+
+func TestEmulationPrevention(t *testing.T) {
+	tests := []struct {
+		rbsp []byte
+		nalu []byte
+	}{
+		{[]byte{0x00, 0x00, 0x00}, []byte{0x00, 0x00, 0x03, 0x00}},
+		{[]byte{0x00, 0x00, 0x01}, []byte{0x00, 0x00, 0x03, 0x01}},
+		{[]byte{0x00, 0x00, 0x02}, []byte{0x00, 0x00, 0x03, 0x02}},
+		{[]byte{0x00, 0x00, 0x03}, []byte{0x00, 0x00, 0x03, 0x03}},
+		{[]byte{0x00, 0x00, 0x04}, []byte{0x00, 0x00, 0x04}},
+		{[]byte{0x00, 0x00, 0x00, 0x00}, []byte{0x00, 0x00, 0x03, 0x00, 0x00, 0x03}},
+		{[]byte{0x00, 0x00, 0x00, 0x00, 0x00}, []byte{0x00, 0x00, 0x03, 0x00, 0x00, 0x03, 0x00}},
+		{[]byte{0x67, 0x00, 0x00, 0x01, 0x00, 0x00, 0x02}, []byte{0x67, 0x00, 0x00, 0x03, 0x01, 0x00, 0x00, 0x03, 0x02}},
+		{[]byte{0x00, 0x01, 0x00, 0x01}, []byte{0x00, 0x01, 0x00, 0x01}},
+		{[]byte{0x00, 0x00}, []byte{0x00, 0x00, 0x03}},
+		{[]byte{0x00}, []byte{0x00}},
+		{[]byte{0x65, 0x88, 0x00, 0x00}, []byte{0x65, 0x88, 0x00, 0x00, 0x03}}, // Trailing cabac_zero_word
+		{[]byte{0x65, 0x00, 0x00, 0x00, 0x00}, []byte{0x65, 0x00, 0x00, 0x03, 0x00, 0x00, 0x03}},
+	}
+
+	for _, test := range tests {
+		if got := InsertEmulationPrevention(test.rbsp); !bytes.Equal(got, test.nalu) {
+			t.Errorf("InsertEmulationPrevention(% x) = % x, want % x", test.rbsp, got, test.nalu)
+		}
+
+		if got := RemoveEmulationPrevention(test.nalu); !bytes.Equal(got, test.rbsp) {
+			t.Errorf("RemoveEmulationPrevention(% x) = % x, want % x", test.nalu, got, test.rbsp)
+		}
+
+		if got := RemoveEmulationPrevention(InsertEmulationPrevention(test.rbsp)); !bytes.Equal(got, test.rbsp) {
+			t.Errorf("round trip of % x = % x", test.rbsp, got)
+		}
+	}
+}
@@ -0,0 +1,114 @@
+package binary
+
+// BitWriter writes bits to a growing byte slice, most significant bit first.
+type BitWriter struct {
+	buf []byte
+	pos int // Position of the next bit to be written
+}
+
+func NewBitWriter() *BitWriter {
+	return &BitWriter{}
+}
+
+// WriteBits writes the lowest n (<= 64) bits of v.
+func (w *BitWriter) WriteBits(v uint64, n int) {
+	for i := n - 1; i >= 0; i-- {
+		if w.pos&7 == 0 {
+			w.buf = append(w.buf, 0)
+		}
+
+		if (v>>uint(i))&1 == 1 {
+			w.buf[w.pos>>3] |= 1 << (7 - uint(w.pos&7))
+		}
+
+		w.pos++
+	}
+}
+
+// WriteFlag writes a boolean as 1 bit.
+func (w *BitWriter) WriteFlag(flag bool) {
+	if flag {
+		w.WriteBits(1, 1)
+	} else {
+		w.WriteBits(0, 1)
+	}
+}
+
+// WriteUE writes an unsigned Exp-Golomb code ue(v).
+func (w *BitWriter) WriteUE(v uint32) {
+	w.writeExpGolomb(uint64(v))
+}
+
+// WriteSE writes a signed Exp-Golomb code se(v).
+func (w *BitWriter) WriteSE(v int32) {
+	// 1, -1, 2, -2, ... are mapped to 1, 2, 3, 4, ...,
+	// the code of math.MinInt32 is 2^32, which does not fit in ue(v)
+	if v > 0 {
+		w.writeExpGolomb(uint64(2*int64(v) - 1))
+	} else {
+		w.writeExpGolomb(uint64(-2 * int64(v)))
+	}
+}
+
+// writeExpGolomb writes v as Exp-Golomb code.
+func (w *BitWriter) writeExpGolomb(v uint64) {
+	x := v + 1
+	length := 0
+
+	for t := x; t > 1; t >>= 1 {
+		length++
+	}
+
+	// length leading zeros followed by x in (length + 1) bits
+	w.WriteBits(0, length)
+	w.WriteBits(x, length+1)
+}
+
+// ByteAligned reports whether the next bit to be written is the first bit of a byte.
+func (w *BitWriter) ByteAligned() bool {
+	return w.pos&7 == 0
+}
+
+// ByteAlign pads the current byte with zero bits.
+func (w *BitWriter) ByteAlign() {
+	w.pos = (w.pos + 7) &^ 7
+}
+
+// Len returns the number of bits written.
+func (w *BitWriter) Len() int {
+	return w.pos
+}
+
+// Bytes returns the written bytes, the last byte is padded with zero bits.
+func (w *BitWriter) Bytes() []byte {
+	return w.buf
+}
+
+// InsertEmulationPrevention inserts emulation prevention bytes into raw byte sequence payload (RBSP),
+// so that the payload contains no start code prefix (0x000000 - 0x000003).
+// 0x03 is appended if RBSP ends in 0x0000 (cabac_zero_word), as NAL unit must not end in 0x00.
+func InsertEmulationPrevention(rbsp []byte) []byte {
+	b := make([]byte, 0, len(rbsp)+len(rbsp)/2+1)
+	zeros := 0
+
+	for _, c := range rbsp {
+		if zeros >= 2 && c <= 0x03 {
+			b = append(b, 0x03)
+			zeros = 0
+		}
+
+		if c == 0 {
+			zeros++
+		} else {
+			zeros = 0
+		}
+
+		b = append(b, c)
+	}
+
+	if zeros >= 2 {
+		b = append(b, 0x03)
+	}
+
+	return b
+}
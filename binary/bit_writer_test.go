@@ -0,0 +1,120 @@
+package binary
+
+import (
+	"bytes"
+	"math"
+	"testing"
+)
+
+func TestWriteBits(t *testing.T) {
+	tests := []struct {
+		name   string
+		writes [][2]uint64 // value, number of bits
+		want   []byte
+		bits   int
+	}{
+		{"0 bits", [][2]uint64{{0xFF, 0}}, nil, 0},
+		{"1 bit", [][2]uint64{{1, 1}}, []byte{0x80}, 1},
+		{"lowest bits only", [][2]uint64{{0xFF, 4}}, []byte{0xF0}, 4},
+		{"across byte boundary", [][2]uint64{{0x5, 3}, {0x1FF, 9}}, []byte{0xBF, 0xF0}, 12},
+		{"32 bits", [][2]uint64{{0xA5FF003C, 32}}, []byte{0xA5, 0xFF, 0x00, 0x3C}, 32},
+		{"32 bits unaligned", [][2]uint64{{0, 4}, {0xA5FF003C, 32}}, []byte{0x0A, 0x5F, 0xF0, 0x03, 0xC0}, 36},
+		{"64 bits", [][2]uint64{{0x0123456789ABCDEF, 64}}, []byte{0x01, 0x23, 0x45, 0x67, 0x89, 0xAB, 0xCD, 0xEF}, 64},
+		{"64 bits unaligned", [][2]uint64{{1, 1}, {math.MaxUint64, 64}}, []byte{0xFF, 0xFF, 0xFF, 0xFF, 0xFF, 0xFF, 0xFF, 0xFF, 0x80}, 65},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			w := NewBitWriter()
+			for _, write := range test.writes {
+				w.WriteBits(write[0], int(write[1]))
+			}
+
+			if !bytes.Equal(w.Bytes(), test.want) || w.Len() != test.bits {
+				t.Errorf("Bytes() = % x, Len() = %d, want % x, %d", w.Bytes(), w.Len(), test.want, test.bits)
+			}
+		})
+	}
+}
+
+func TestWriteReadRoundTrip(t *testing.T) {
+	writes := [][2]uint64{{1, 1}, {0, 0}, {0x3, 2}, {0x1234, 13}, {0xDEADBEEF, 32}, {0x0123456789ABCDEF, 64}, {0, 5}}
+
+	w := NewBitWriter()
+	for _, write := range writes {
+		w.WriteBits(write[0], int(write[1]))
+	}
+
+	r := NewBitReader(w.Bytes())
+	for _, write := range writes {
+		got, err := r.ReadBits64(int(write[1]))
+		if err != nil || got != write[0] {
+			t.Errorf("ReadBits64(%d) = %#x, %v, want %#x", write[1], got, err, write[0])
+		}
+	}
+}
+
+func TestBitWriterByteAlign(t *testing.T) {
+	w := NewBitWriter()
+	w.WriteFlag(true)
+
+	if w.ByteAligned() {
+		t.Error("ByteAligned() = true after 1 bit")
+	}
+
+	w.ByteAlign()
+	w.WriteFlag(true)
+	w.WriteFlag(false)
+
+	if !bytes.Equal(w.Bytes(), []byte{0x80, 0x80}) || w.Len() != 10 {
+		t.Errorf("Bytes() = % x, Len() = %d", w.Bytes(), w.Len())
+	}
+
+	w.ByteAlign()
+	if !w.ByteAligned() || w.Len() != 16 {
+		t.Errorf("after ByteAlign() Len() = %d, want 16", w.Len())
+	}
+}
+
+func TestWriteUE(t *testing.T) {
+	tests := []struct {
+		v    uint32
+		want []byte
+		bits int
+	}{
+		{0, []byte{0x80}, 1},
+		{1, []byte{0x40}, 3},
+		{2, []byte{0x60}, 3},
+		{3, []byte{0x20}, 5},
+		{6, []byte{0x38}, 5},
+		{16, []byte{0x08, 0x80}, 9},
+		// 2^32 - 2 is the largest ue(v), coded with 31 leading zeros
+		{math.MaxUint32 - 1, []byte{0x00, 0x00, 0x00, 0x01, 0xFF, 0xFF, 0xFF, 0xFE}, 63},
+	}
+
+	for _, test := range tests {
+		w := NewBitWriter()
+		w.WriteUE(test.v)
+
+		if !bytes.Equal(w.Bytes(), test.want) || w.Len() != test.bits {
+			t.Errorf("WriteUE(%d) = % x (%d bits), want % x (%d bits)", test.v, w.Bytes(), w.Len(), test.want, test.bits)
+		}
+	}
+}
+
+func TestWriteSEMinInt32(t *testing.T) {
+	// math.MinInt32 is coded as 2^32 with 32 leading zeros, rather than wrapping to 0
+	w := NewBitWriter()
+	w.WriteSE(math.MinInt32)
+
+	if w.Len() != 65 {
+		t.Errorf("WriteSE(math.MinInt32) wrote %d bits, want 65", w.Len())
+	}
+
+	zero := NewBitWriter()
+	zero.WriteSE(0)
+
+	if bytes.Equal(w.Bytes(), zero.Bytes()) {
+		t.Error("WriteSE(math.MinInt32) is coded as WriteSE(0)")
+	}
+}
@@ -0,0 +1,106 @@
+package binary
+
+import (
+	"io"
+)
+
+// Reader reads integers and bytes from a byte slice with a cursor,
+// reads beyond the end of slice return io.ErrUnexpectedEOF instead of panicking.
+type Reader struct {
+	buf []byte
+	pos int // Position of the next byte to be read
+}
+
+func NewReader(b []byte) *Reader {
+	return &Reader{buf: b}
+}
+
+// Len returns the number of bytes not read yet.
+func (r *Reader) Len() int {
+	return len(r.buf) - r.pos
+}
+
+// Pos returns the number of bytes read.
+func (r *Reader) Pos() int {
+	return r.pos
+}
+
+// Bytes reads n bytes, the returned slice shares the underlying array of the reader.
+func (r *Reader) Bytes(n int) ([]byte, error) {
+	if n < 0 || n > r.Len() {
+		return nil, io.ErrUnexpectedEOF
+	}
+
+	b := r.buf[r.pos : r.pos+n]
+	r.pos += n
+
+	return b, nil
+}
+
+// Rest reads all bytes not read yet.
+func (r *Reader) Rest() []byte {
+	b := r.buf[r.pos:]
+	r.pos = len(r.buf)
+
+	return b
+}
+
+// Skip skips n bytes.
+func (r *Reader) Skip(n int) error {
+	_, err := r.Bytes(n)
+	return err
+}
+
+func (r *Reader) U8() (uint8, error) {
+	b, err := r.Bytes(1)
+	if err != nil {
+		return 0, err
+	}
+
+	return U8(b), nil
+}
+
+func (r *Reader) U16BE() (uint16, error) {
+	b, err := r.Bytes(2)
+	if err != nil {
+		return 0, err
+	}
+
+	return U16BE(b), nil
+}
+
+func (r *Reader) U24BE() (uint32, error) {
+	b, err := r.Bytes(3)
+	if err != nil {
+		return 0, err
+	}
+
+	return U24BE(b), nil
+}
+
+func (r *Reader) U32BE() (uint32, error) {
+	b, err := r.Bytes(4)
+	if err != nil {
+		return 0, err
+	}
+
+	return U32BE(b), nil
+}
+
+func (r *Reader) I24BE() (int32, error) {
+	b, err := r.Bytes(3)
+	if err != nil {
+		return 0, err
+	}
+
+	return I24BE(b), nil
+}
+
+func (r *Reader) U32LE() (uint32, error) {
+	b, err := r.Bytes(4)
+	if err != nil {
+		return 0, err
+	}
+
+	return U32LE(b), nil
+}
@@ -0,0 +1,54 @@
+package binary
+
+// Writer appends integers and bytes to a growing byte slice.
+type Writer struct {
+	buf []byte
+}
+
+func NewWriter(capacity int) *Writer {
+	return &Writer{buf: make([]byte, 0, capacity)}
+}
+
+// Bytes returns the written bytes.
+func (w *Writer) Bytes() []byte {
+	return w.buf
+}
+
+// Len returns the number of bytes written.
+func (w *Writer) Len() int {
+	return len(w.buf)
+}
+
+// grow extends the written bytes by n bytes and returns the extended bytes.
+func (w *Writer) grow(n int) []byte {
+	w.buf = append(w.buf, make([]byte, n)...)
+	return w.buf[len(w.buf)-n:]
+}
+
+func (w *Writer) PutBytes(b []byte) {
+	w.buf = append(w.buf, b...)
+}
+
+func (w *Writer) PutU8(v uint8) {
+	w.buf = append(w.buf, v)
+}
+
+func (w *Writer) PutU16BE(v uint16) {
+	PutU16BE(w.grow(2), v)
+}
+
+func (w *Writer) PutU24BE(v uint32) {
+	PutU24BE(w.grow(3), v)
+}
+
+func (w *Writer) PutU32BE(v uint32) {
+	PutU32BE(w.grow(4), v)
+}
+
+func (w *Writer) PutI24BE(v int32) {
+	PutI24BE(w.grow(3), v)
+}
+
+func (w *Writer) PutU32LE(v uint32) {
+	PutU32LE(w.grow(4), v)
+}
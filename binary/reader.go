@@ -84,7 +84,7 @@ func I16BE(b []byte) (i int16) {
 }
 
 func I24BE(b []byte) (i int32) {
-	i = int32(b[0]) << 16
+	i = int32(int8(b[0])) << 16 // Sign extended
 	i |= int32(b[1]) << 8
 	i |= int32(b[2])
 	return
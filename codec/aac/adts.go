@@ -0,0 +1,130 @@
+package aac
+
+import (
+	"errors"
+	"io"
+)
+
+const (
+	adtsHeaderLen      = 7
+	adtsCRCLen         = 2
+	adtsMaxFrameLength = 1<<13 - 1
+)
+
+var (
+	ErrInvalidADTS     = errors.New("Invalid ADTS header")
+	ErrADTSUnsupported = errors.New("Audio config cannot be represented in ADTS")
+)
+
+// ADTSHeader is the fixed and variable header of an ADTS frame.
+type ADTSHeader struct {
+	ObjectType      uint8
+	SampleRateIndex uint8
+	ChannelConfig   uint8
+	HeaderLength    int // 9 if CRC is present, otherwise 7
+	FrameLength     int // Length of the frame, including header
+}
+
+// ParseADTSHeader parses the header of ADTS frame at the beginning of b.
+func ParseADTSHeader(b []byte) (*ADTSHeader, error) {
+	if len(b) < adtsHeaderLen {
+		return nil, io.ErrUnexpectedEOF
+	}
+
+	// Syncword: 0xFFF, layer: 0
+	if b[0] != 0xff || b[1]&0xf6 != 0xf0 {
+		return nil, ErrInvalidADTS
+	}
+
+	header := &ADTSHeader{
+		ObjectType:      (b[2] >> 6) + 1,
+		SampleRateIndex: (b[2] >> 2) & 0xf,
+		ChannelConfig:   (b[2]&0x1)<<2 | b[3]>>6,
+		HeaderLength:    adtsHeaderLen,
+		FrameLength:     int(b[3]&0x3)<<11 | int(b[4])<<3 | int(b[5]>>5),
+	}
+
+	// Protection absent
+	if b[1]&0x1 == 0 {
+		header.HeaderLength += adtsCRCLen
+	}
+
+	if int(header.SampleRateIndex) >= len(sampleRates) ||
+		header.FrameLength < header.HeaderLength {
+		return nil, ErrInvalidADTS
+	}
+
+	return header, nil
+}
+
+// Config returns the audio config signalled in ADTS header.
+func (header *ADTSHeader) Config() *AudioSpecificConfig {
+	return &AudioSpecificConfig{
+		ObjectType:      header.ObjectType,
+		SampleRateIndex: header.SampleRateIndex,
+		SampleRate:      sampleRates[header.SampleRateIndex],
+		ChannelConfig:   header.ChannelConfig,
+	}
+}
+
+// ToADTS prepends ADTS header to a raw AAC frame of config.
+func ToADTS(config *AudioSpecificConfig, frame []byte) ([]byte, error) {
+	if config.ObjectType < ObjectTypeAACMain || config.ObjectType > ObjectTypeAACLTP ||
+		int(config.SampleRateIndex) >= len(sampleRates) || config.ChannelConfig > 7 {
+		return nil, ErrADTSUnsupported
+	}
+
+	frameLength := adtsHeaderLen + len(frame)
+	if frameLength > adtsMaxFrameLength {
+		return nil, ErrADTSUnsupported
+	}
+
+	result := make([]byte, frameLength)
+
+	// Syncword, MPEG-4, layer 0, protection absent
+	result[0] = 0xff
+	result[1] = 0xf1
+	result[2] = (config.ObjectType-1)<<6 |
+		config.SampleRateIndex<<2 |
+		config.ChannelConfig>>2
+	result[3] = config.ChannelConfig<<6 | byte(frameLength>>11)
+	result[4] = byte(frameLength >> 3)
+	// Buffer fullness 0x7FF, i.e. variable bitrate, one raw data block
+	result[5] = byte(frameLength)<<5 | 0x1f
+	result[6] = 0xfc
+
+	copy(result[adtsHeaderLen:], frame)
+
+	return result, nil
+}
+
+// FromADTS splits ADTS stream into raw AAC frames,
+// returns the audio config of the first frame along with the frames.
+func FromADTS(b []byte) (*AudioSpecificConfig, [][]byte, error) {
+	var config *AudioSpecificConfig
+	var frames [][]byte
+
+	for len(b) > 0 {
+		header, err := ParseADTSHeader(b)
+		if err != nil {
+			return nil, nil, err
+		}
+
+		if len(b) < header.FrameLength {
+			return nil, nil, io.ErrUnexpectedEOF
+		}
+
+		if config == nil {
+			config = header.Config()
+		}
+
+		frames = append(frames, b[header.HeaderLength:header.FrameLength])
+		b = b[header.FrameLength:]
+	}
+
+	if config == nil {
+		return nil, nil, io.ErrUnexpectedEOF
+	}
+
+	return config, frames, nil
+}
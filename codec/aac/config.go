@@ -0,0 +1,275 @@
+package aac
+
+import (
+	"errors"
+
+	bin "github.com/frankchang0125/go-live-stream/binary"
+)
+
+var ErrInvalidConfig = errors.New("Invalid AudioSpecificConfig")
+
+// Audio object types
+const (
+	ObjectTypeAACMain  = 1
+	ObjectTypeAACLC    = 2
+	ObjectTypeAACSSR   = 3
+	ObjectTypeAACLTP   = 4
+	ObjectTypeSBR      = 5 // HE-AAC
+	ObjectTypeScalable = 6
+	ObjectTypeTwinVQ   = 7
+	ObjectTypePS       = 29 // HE-AAC v2
+)
+
+// explicitSampleRate is the sampling frequency index of explicitly signalled sample rate.
+const explicitSampleRate = 0xf
+
+var sampleRates = []int{
+	96000, 88200, 64000, 48000, 44100, 32000,
+	24000, 22050, 16000, 12000, 11025, 8000, 7350,
+}
+
+// AudioSpecificConfig is the AAC sequence header carried in
+// audio tag of AACPacketType 0, defined in ISO/IEC 14496-3.
+type AudioSpecificConfig struct {
+	ObjectType          uint8 // Object type of the core codec, e.g. AAC-LC for HE-AAC
+	SampleRateIndex     uint8 // 15 if sample rate is explicitly signalled
+	SampleRate          int   // Sample rate of the core codec
+	ChannelConfig       uint8 // 0 if channels are defined in program config element
+	SBR                 bool  // Spectral band replication is present (HE-AAC)
+	PS                  bool  // Parametric stereo is present (HE-AAC v2)
+	ExtensionSampleRate int   // Output sample rate of SBR, 0 if SBR is absent
+}
+
+// ParseAudioSpecificConfig parses AAC sequence header, both explicit
+// and backward compatible SBR/PS signalling are detected.
+func ParseAudioSpecificConfig(b []byte) (*AudioSpecificConfig, error) {
+	r := bin.NewBitReader(b)
+	config := &AudioSpecificConfig{}
+
+	objectType, err := readObjectType(r)
+	if err != nil {
+		return nil, err
+	}
+
+	config.SampleRateIndex, config.SampleRate, err = readSampleRate(r)
+	if err != nil {
+		return nil, err
+	}
+
+	channelConfig, err := r.ReadBits(4)
+	if err != nil {
+		return nil, err
+	}
+
+	config.ChannelConfig = uint8(channelConfig)
+
+	// Explicit hierarchical signalling of SBR/PS
+	if objectType == ObjectTypeSBR || objectType == ObjectTypePS {
+		config.SBR = true
+		config.PS = objectType == ObjectTypePS
+
+		_, config.ExtensionSampleRate, err = readSampleRate(r)
+		if err != nil {
+			return nil, err
+		}
+
+		objectType, err = readObjectType(r)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	config.ObjectType = objectType
+
+	switch objectType {
+	case ObjectTypeAACMain, ObjectTypeAACLC, ObjectTypeAACSSR,
+		ObjectTypeAACLTP, ObjectTypeScalable, ObjectTypeTwinVQ:
+	default:
+		// Other object types are not parsed any further
+		return config, nil
+	}
+
+	// Program config element is not supported, thus backward compatible signalling
+	// following GASpecificConfig cannot be located
+	if config.ChannelConfig == 0 {
+		return config, nil
+	}
+
+	err = skipGASpecificConfig(r, objectType)
+	if err != nil {
+		return nil, err
+	}
+
+	if !config.SBR {
+		err = readSyncExtension(r, config)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	return config, nil
+}
+
+// Channels returns the number of output channels, 0 if unknown.
+func (config *AudioSpecificConfig) Channels() int {
+	if config.PS {
+		return 2
+	}
+
+	switch {
+	case config.ChannelConfig >= 1 && config.ChannelConfig <= 6:
+		return int(config.ChannelConfig)
+	case config.ChannelConfig == 7:
+		return 8
+	}
+
+	return 0
+}
+
+// OutputSampleRate returns the sample rate of decoded audio, which is doubled by SBR.
+func (config *AudioSpecificConfig) OutputSampleRate() int {
+	if config.SBR && config.ExtensionSampleRate > 0 {
+		return config.ExtensionSampleRate
+	}
+
+	return config.SampleRate
+}
+
+// Encode encodes the core codec configuration into AAC sequence header,
+// SBR/PS are left for implicit signalling.
+func (config *AudioSpecificConfig) Encode() []byte {
+	w := bin.NewBitWriter()
+
+	w.WriteBits(uint64(config.ObjectType), 5)
+	w.WriteBits(uint64(config.SampleRateIndex), 4)
+	if config.SampleRateIndex == explicitSampleRate {
+		w.WriteBits(uint64(config.SampleRate), 24)
+	}
+	w.WriteBits(uint64(config.ChannelConfig), 4)
+
+	// GASpecificConfig: frameLengthFlag, dependsOnCoreCoder, extensionFlag
+	w.WriteBits(0, 3)
+	w.ByteAlign()
+
+	return w.Bytes()
+}
+
+func readObjectType(r *bin.BitReader) (uint8, error) {
+	objectType, err := r.ReadBits(5)
+	if err != nil {
+		return 0, err
+	}
+
+	if objectType == 31 {
+		ext, err := r.ReadBits(6)
+		if err != nil {
+			return 0, err
+		}
+
+		objectType = 32 + ext
+	}
+
+	return uint8(objectType), nil
+}
+
+func readSampleRate(r *bin.BitReader) (uint8, int, error) {
+	index, err := r.ReadBits(4)
+	if err != nil {
+		return 0, 0, err
+	}
+
+	if index == explicitSampleRate {
+		sampleRate, err := r.ReadBits(24)
+		if err != nil {
+			return 0, 0, err
+		}
+
+		return uint8(index), int(sampleRate), nil
+	}
+
+	if int(index) >= len(sampleRates) {
+		return 0, 0, ErrInvalidConfig
+	}
+
+	return uint8(index), sampleRates[index], nil
+}
+
+func skipGASpecificConfig(r *bin.BitReader, objectType uint8) error {
+	// frameLengthFlag
+	err := r.Skip(1)
+	if err != nil {
+		return err
+	}
+
+	dependsOnCoreCoder, err := r.ReadFlag()
+	if err != nil {
+		return err
+	}
+
+	if dependsOnCoreCoder {
+		// coreCoderDelay
+		err = r.Skip(14)
+		if err != nil {
+			return err
+		}
+	}
+
+	// extensionFlag
+	err = r.Skip(1)
+	if err != nil {
+		return err
+	}
+
+	if objectType == ObjectTypeScalable {
+		// layerNr
+		err = r.Skip(3)
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// readSyncExtension reads backward compatible signalling of SBR/PS
+// appended after GASpecificConfig, if any.
+func readSyncExtension(r *bin.BitReader, config *AudioSpecificConfig) error {
+	if r.BitsLeft() < 16 {
+		return nil
+	}
+
+	syncExtensionType, _ := r.ReadBits(11)
+	if syncExtensionType != 0x2b7 {
+		return nil
+	}
+
+	extensionObjectType, err := readObjectType(r)
+	if err != nil {
+		return err
+	}
+
+	if extensionObjectType != ObjectTypeSBR {
+		return nil
+	}
+
+	config.SBR, err = r.ReadFlag()
+	if err != nil || !config.SBR {
+		return err
+	}
+
+	_, config.ExtensionSampleRate, err = readSampleRate(r)
+	if err != nil {
+		return err
+	}
+
+	if r.BitsLeft() < 12 {
+		return nil
+	}
+
+	syncExtensionType, _ = r.ReadBits(11)
+	if syncExtensionType == 0x548 {
+		config.PS, err = r.ReadFlag()
+	}
+
+	return err
+}
@@ -0,0 +1,189 @@
+package aac
+
+import (
+	"bytes"
+	"encoding/hex"
+	"io"
+	"testing"
+)
+
+func mustDecodeHex(s string) []byte {
+	b, err := hex.DecodeString(s)
+	if err != nil {
+		panic(err)
+	}
+
+	return b
+}
+
+func TestParseAudioSpecificConfig(t *testing.T) {
+	tests := []struct {
+		name     string
+		asc      string
+		config   AudioSpecificConfig
+		channels int
+		rate     int
+	}{
+		{"AAC-LC 44.1 kHz stereo", "1210",
+			AudioSpecificConfig{ObjectType: ObjectTypeAACLC, SampleRateIndex: 4, SampleRate: 44100, ChannelConfig: 2}, 2, 44100},
+		{"AAC-LC 48 kHz 5.1", "11b0",
+			AudioSpecificConfig{ObjectType: ObjectTypeAACLC, SampleRateIndex: 3, SampleRate: 48000, ChannelConfig: 6}, 6, 48000},
+		{"explicit frequency", "1780562210",
+			AudioSpecificConfig{ObjectType: ObjectTypeAACLC, SampleRateIndex: 15, SampleRate: 44100, ChannelConfig: 2}, 2, 44100},
+		{"explicit SBR", "2b118800",
+			AudioSpecificConfig{ObjectType: ObjectTypeAACLC, SampleRateIndex: 6, SampleRate: 24000, ChannelConfig: 2,
+				SBR: true, ExtensionSampleRate: 48000}, 2, 48000},
+		{"explicit SBR with explicit frequencies", "2f802b11178056220800",
+			AudioSpecificConfig{ObjectType: ObjectTypeAACLC, SampleRateIndex: 15, SampleRate: 22050, ChannelConfig: 2,
+				SBR: true, ExtensionSampleRate: 44100}, 2, 44100},
+		{"explicit PS", "eb098800",
+			AudioSpecificConfig{ObjectType: ObjectTypeAACLC, SampleRateIndex: 6, SampleRate: 24000, ChannelConfig: 1,
+				SBR: true, PS: true, ExtensionSampleRate: 48000}, 2, 48000},
+		{"backward compatible SBR", "131056e598",
+			AudioSpecificConfig{ObjectType: ObjectTypeAACLC, SampleRateIndex: 6, SampleRate: 24000, ChannelConfig: 2,
+				SBR: true, ExtensionSampleRate: 48000}, 2, 48000},
+		{"backward compatible PS", "130856e59d4880",
+			AudioSpecificConfig{ObjectType: ObjectTypeAACLC, SampleRateIndex: 6, SampleRate: 24000, ChannelConfig: 1,
+				SBR: true, PS: true, ExtensionSampleRate: 48000}, 2, 48000},
+	}
+
+	for _, test := range tests {
+		config, err := ParseAudioSpecificConfig(mustDecodeHex(test.asc))
+		if err != nil {
+			t.Errorf("%s: ParseAudioSpecificConfig() = %v", test.name, err)
+			continue
+		}
+
+		if *config != test.config {
+			t.Errorf("%s: ParseAudioSpecificConfig() = %+v, want %+v", test.name, *config, test.config)
+		}
+
+		if config.Channels() != test.channels || config.OutputSampleRate() != test.rate {
+			t.Errorf("%s: %d channels, %d Hz, want %d channels, %d Hz", test.name,
+				config.Channels(), config.OutputSampleRate(), test.channels, test.rate)
+		}
+	}
+}
+
+func TestParseAudioSpecificConfigInvalid(t *testing.T) {
+	tests := []struct {
+		name string
+		asc  []byte
+		err  error
+	}{
+		{"empty", nil, io.ErrUnexpectedEOF},
+		{"1 byte", []byte{0x12}, io.ErrUnexpectedEOF},
+		{"truncated explicit frequency", mustDecodeHex("178056"), io.ErrUnexpectedEOF},
+		{"reserved frequency index", []byte{0x16, 0x90}, ErrInvalidConfig},
+	}
+
+	for _, test := range tests {
+		if _, err := ParseAudioSpecificConfig(test.asc); err != test.err {
+			t.Errorf("%s: ParseAudioSpecificConfig(% x) = %v, want %v", test.name, test.asc, err, test.err)
+		}
+	}
+}
+
+func TestEncodeAudioSpecificConfig(t *testing.T) {
+	for _, asc := range []string{"1210", "11b0", "1780562210"} {
+		config, err := ParseAudioSpecificConfig(mustDecodeHex(asc))
+		if err != nil {
+			t.Fatalf("ParseAudioSpecificConfig(%s) = %v", asc, err)
+		}
+
+		if encoded := hex.EncodeToString(config.Encode()); encoded != asc {
+			t.Errorf("Encode() of %s = %s", asc, encoded)
+		}
+	}
+}
+
+func TestADTSRoundTrip(t *testing.T) {
+	for _, asc := range []string{"1210", "11b0", "1408", "131056e598"} {
+		config, err := ParseAudioSpecificConfig(mustDecodeHex(asc))
+		if err != nil {
+			t.Fatalf("ParseAudioSpecificConfig(%s) = %v", asc, err)
+		}
+
+		frames := [][]byte{bytes.Repeat([]byte{0x21}, 300), {0x01, 0x02}, {}}
+
+		var stream []byte
+		for _, frame := range frames {
+			adts, err := ToADTS(config, frame)
+			if err != nil {
+				t.Fatalf("%s: ToADTS() = %v", asc, err)
+			}
+
+			stream = append(stream, adts...)
+		}
+
+		got, gotFrames, err := FromADTS(stream)
+		if err != nil {
+			t.Fatalf("%s: FromADTS() = %v", asc, err)
+		}
+
+		// SBR and PS are left for implicit signalling in ADTS
+		core := AudioSpecificConfig{
+			ObjectType:      config.ObjectType,
+			SampleRateIndex: config.SampleRateIndex,
+			SampleRate:      config.SampleRate,
+			ChannelConfig:   config.ChannelConfig,
+		}
+
+		if *got != core {
+			t.Errorf("%s: FromADTS() config = %+v, want %+v", asc, *got, core)
+		}
+
+		if len(gotFrames) != len(frames) {
+			t.Fatalf("%s: FromADTS() = %d frames, want %d", asc, len(gotFrames), len(frames))
+		}
+
+		for i := range frames {
+			if !bytes.Equal(gotFrames[i], frames[i]) {
+				t.Errorf("%s: frame %d = % x, want % x", asc, i, gotFrames[i], frames[i])
+			}
+		}
+
+		// ASC -> ADTS -> ASC
+		if encoded := hex.EncodeToString(got.Encode()); encoded != asc[:4] {
+			t.Errorf("%s: Encode() of ADTS config = %s, want %s", asc, encoded, asc[:4])
+		}
+	}
+}
+
+func TestADTSInvalid(t *testing.T) {
+	explicit, _ := ParseAudioSpecificConfig(mustDecodeHex("1780562210"))
+	if _, err := ToADTS(explicit, []byte{0x01}); err != ErrADTSUnsupported {
+		t.Errorf("ToADTS() of explicit frequency = %v, want %v", err, ErrADTSUnsupported)
+	}
+
+	lc, _ := ParseAudioSpecificConfig(mustDecodeHex("1210"))
+	if _, err := ToADTS(lc, make([]byte, adtsMaxFrameLength)); err != ErrADTSUnsupported {
+		t.Errorf("ToADTS() of oversized frame = %v, want %v", err, ErrADTSUnsupported)
+	}
+
+	adts, _ := ToADTS(lc, []byte{0x01, 0x02, 0x03})
+
+	// Header with CRC
+	crc := append([]byte{}, adts...)
+	crc[1] &^= 0x1
+	if header, err := ParseADTSHeader(crc); err != nil || header.HeaderLength != adtsHeaderLen+adtsCRCLen {
+		t.Errorf("ParseADTSHeader() with CRC = %+v, %v", header, err)
+	}
+
+	tests := []struct {
+		name   string
+		stream []byte
+		err    error
+	}{
+		{"empty", nil, io.ErrUnexpectedEOF},
+		{"short header", adts[:6], io.ErrUnexpectedEOF},
+		{"truncated frame", adts[:len(adts)-1], io.ErrUnexpectedEOF},
+		{"invalid syncword", append([]byte{0xfe}, adts[1:]...), ErrInvalidADTS},
+	}
+
+	for _, test := range tests {
+		if _, _, err := FromADTS(test.stream); err != test.err {
+			t.Errorf("%s: FromADTS() = %v, want %v", test.name, err, test.err)
+		}
+	}
+}
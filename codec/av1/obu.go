@@ -0,0 +1,129 @@
+package av1
+
+import (
+	"errors"
+	"io"
+
+	bin "github.com/frankchang0125/go-live-stream/binary"
+)
+
+var ErrInvalidOBU = errors.New("Invalid OBU")
+
+// OBUType is the type of open bitstream unit defined in AV1 specification 6.2.2.
+type OBUType uint8
+
+const (
+	OBUTypeSequenceHeader       OBUType = 1
+	OBUTypeTemporalDelimiter    OBUType = 2
+	OBUTypeFrameHeader          OBUType = 3
+	OBUTypeTileGroup            OBUType = 4
+	OBUTypeMetadata             OBUType = 5
+	OBUTypeFrame                OBUType = 6
+	OBUTypeRedundantFrameHeader OBUType = 7
+	OBUTypeTileList             OBUType = 8
+	OBUTypePadding              OBUType = 15
+)
+
+// OBU is an open bitstream unit.
+type OBU struct {
+	Type    OBUType
+	Payload []byte
+}
+
+// SplitOBUs splits a temporal unit in low overhead bitstream format into OBUs.
+func SplitOBUs(data []byte) ([]OBU, error) {
+	r := bin.NewReader(data)
+	var obus []OBU
+
+	for r.Len() > 0 {
+		header, err := r.U8()
+		if err != nil {
+			return nil, err
+		}
+
+		// obu_forbidden_bit
+		if header&0x80 != 0 {
+			return nil, ErrInvalidOBU
+		}
+
+		obu := OBU{Type: OBUType((header >> 3) & 0xf)}
+
+		// obu_extension_flag
+		if header&0x4 != 0 {
+			err = r.Skip(1)
+			if err != nil {
+				return nil, err
+			}
+		}
+
+		// obu_has_size_field, otherwise OBU extends to the end of data
+		if header&0x2 != 0 {
+			size, err := readLEB128(r)
+			if err != nil {
+				return nil, err
+			}
+
+			obu.Payload, err = r.Bytes(int(size))
+			if err != nil {
+				return nil, err
+			}
+		} else {
+			obu.Payload = r.Rest()
+		}
+
+		obus = append(obus, obu)
+	}
+
+	return obus, nil
+}
+
+// IsKeyFrame reports whether temporal unit starts a key frame,
+// i.e. carries a sequence header and a frame header of KEY_FRAME type.
+func IsKeyFrame(data []byte) (bool, error) {
+	obus, err := SplitOBUs(data)
+	if err != nil {
+		return false, err
+	}
+
+	hasSequenceHeader := false
+
+	for _, obu := range obus {
+		switch obu.Type {
+		case OBUTypeSequenceHeader:
+			hasSequenceHeader = true
+		case OBUTypeFrameHeader, OBUTypeFrame:
+			if len(obu.Payload) == 0 {
+				return false, io.ErrUnexpectedEOF
+			}
+
+			// show_existing_frame (1 bit) followed by frame_type (2 bits), KEY_FRAME is 0,
+			// assuming reduced_still_picture_header is not set
+			showExistingFrame := obu.Payload[0]&0x80 != 0
+			frameType := (obu.Payload[0] >> 5) & 0x3
+
+			return hasSequenceHeader && !showExistingFrame && frameType == 0, nil
+		}
+	}
+
+	return false, nil
+}
+
+// readLEB128 reads an unsigned little endian base 128 integer.
+func readLEB128(r *bin.Reader) (uint64, error) {
+	var v uint64
+
+	for i := 0; i < 8; i++ {
+		b, err := r.U8()
+		if err != nil {
+			return 0, err
+		}
+
+		v |= uint64(b&0x7f) << (7 * uint(i))
+
+		if b&0x80 == 0 {
+			return v, nil
+		}
+	}
+
+	return 0, ErrInvalidOBU
+}
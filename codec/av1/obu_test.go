@@ -0,0 +1,95 @@
+package av1
+
+import (
+	"bytes"
+	"io"
+	"testing"
+)
+
+var (
+	obuTemporalDelimiter = []byte{0x12, 0x00}
+	// Sequence header of Main profile, 8-bit 4:2:0
+	obuSequenceHeader = []byte{0x0a, 0x0b, 0x00, 0x00, 0x00, 0x24, 0xc4, 0xff, 0xdf, 0x00, 0x68, 0x02, 0x10}
+	// Frame OBUs, show_existing_frame: 0, frame_type: KEY_FRAME / INTER_FRAME, show_frame: 1
+	obuKeyFrame   = []byte{0x32, 0x03, 0x10, 0x00, 0x00}
+	obuInterFrame = []byte{0x32, 0x03, 0x30, 0x00, 0x00}
+)
+
+func temporalUnit(obus ...[]byte) []byte {
+	return bytes.Join(obus, nil)
+}
+
+func TestSplitOBUs(t *testing.T) {
+	// OBU with extension header, and the last OBU without size field
+	data := temporalUnit(obuTemporalDelimiter, obuSequenceHeader, []byte{0x36, 0x08, 0x02, 0x10, 0x00}, []byte{0x30, 0x30, 0x01})
+
+	obus, err := SplitOBUs(data)
+	if err != nil {
+		t.Fatalf("SplitOBUs() = %v", err)
+	}
+
+	want := []OBU{
+		{OBUTypeTemporalDelimiter, []byte{}},
+		{OBUTypeSequenceHeader, obuSequenceHeader[2:]},
+		{OBUTypeFrame, []byte{0x10, 0x00}},
+		{OBUTypeFrame, []byte{0x30, 0x01}},
+	}
+
+	if len(obus) != len(want) {
+		t.Fatalf("SplitOBUs() = %d OBUs, want %d", len(obus), len(want))
+	}
+
+	for i := range want {
+		if obus[i].Type != want[i].Type || !bytes.Equal(obus[i].Payload, want[i].Payload) {
+			t.Errorf("OBU %d = %d, % x, want %d, % x", i, obus[i].Type, obus[i].Payload, want[i].Type, want[i].Payload)
+		}
+	}
+}
+
+func TestSplitOBUsInvalid(t *testing.T) {
+	tests := []struct {
+		name string
+		data []byte
+		err  error
+	}{
+		{"forbidden bit", []byte{0x92, 0x00}, ErrInvalidOBU},
+		{"missing extension header", []byte{0x36}, io.ErrUnexpectedEOF},
+		{"missing size", []byte{0x32}, io.ErrUnexpectedEOF},
+		{"truncated LEB128 size", []byte{0x32, 0x80}, io.ErrUnexpectedEOF},
+		{"LEB128 size over 8 bytes", []byte{0x32, 0x80, 0x80, 0x80, 0x80, 0x80, 0x80, 0x80, 0x80, 0x00}, ErrInvalidOBU},
+		{"size beyond data", []byte{0x32, 0x05, 0x10}, io.ErrUnexpectedEOF},
+	}
+
+	for _, test := range tests {
+		if _, err := SplitOBUs(test.data); err != test.err {
+			t.Errorf("%s: SplitOBUs() = %v, want %v", test.name, err, test.err)
+		}
+	}
+}
+
+func TestIsKeyFrame(t *testing.T) {
+	tests := []struct {
+		name     string
+		data     []byte
+		keyFrame bool
+	}{
+		{"sequence header and key frame", temporalUnit(obuTemporalDelimiter, obuSequenceHeader, obuKeyFrame), true},
+		{"key frame without sequence header", temporalUnit(obuTemporalDelimiter, obuKeyFrame), false},
+		{"inter frame", temporalUnit(obuTemporalDelimiter, obuInterFrame), false},
+		{"sequence header and inter frame", temporalUnit(obuSequenceHeader, obuInterFrame), false},
+		{"show existing key frame", temporalUnit(obuSequenceHeader, []byte{0x32, 0x01, 0x80}), false},
+		{"sequence header and frame header", temporalUnit(obuSequenceHeader, []byte{0x1a, 0x01, 0x10}), true},
+		{"temporal delimiter only", obuTemporalDelimiter, false},
+	}
+
+	for _, test := range tests {
+		keyFrame, err := IsKeyFrame(test.data)
+		if err != nil || keyFrame != test.keyFrame {
+			t.Errorf("%s: IsKeyFrame() = %v, %v, want %v", test.name, keyFrame, err, test.keyFrame)
+		}
+	}
+
+	if _, err := IsKeyFrame(temporalUnit(obuSequenceHeader, []byte{0x32, 0x00})); err != io.ErrUnexpectedEOF {
+		t.Errorf("IsKeyFrame() of empty frame = %v, want %v", err, io.ErrUnexpectedEOF)
+	}
+}
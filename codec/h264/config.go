@@ -0,0 +1,130 @@
+package h264
+
+import (
+	"errors"
+	"io"
+
+	bin "github.com/frankchang0125/go-live-stream/binary"
+)
+
+var ErrInvalidConfig = errors.New("Invalid AVCDecoderConfigurationRecord")
+
+// AVCDecoderConfigurationRecord is the AVC sequence header carried in
+// video tag of AVCPacketType 0, defined in ISO/IEC 14496-15.
+type AVCDecoderConfigurationRecord struct {
+	ConfigurationVersion uint8
+	ProfileIndication    uint8
+	ProfileCompatibility uint8
+	LevelIndication      uint8
+	NALULengthSize       int      // Size of length prefix of each NAL unit in coded frames
+	SPS                  [][]byte // Sequence parameter set NAL units
+	PPS                  [][]byte // Picture parameter set NAL units
+}
+
+// ParseAVCDecoderConfigurationRecord parses AVC sequence header.
+func ParseAVCDecoderConfigurationRecord(b []byte) (*AVCDecoderConfigurationRecord, error) {
+	if len(b) < 7 {
+		return nil, io.ErrUnexpectedEOF
+	}
+
+	if b[0] != 1 {
+		return nil, ErrInvalidConfig
+	}
+
+	record := &AVCDecoderConfigurationRecord{
+		ConfigurationVersion: b[0],
+		ProfileIndication:    b[1],
+		ProfileCompatibility: b[2],
+		LevelIndication:      b[3],
+		NALULengthSize:       int(b[4]&0x3) + 1,
+	}
+
+	if record.NALULengthSize == 3 {
+		return nil, ErrInvalidConfig
+	}
+
+	numOfSPS := int(b[5] & 0x1f)
+	pos := 6
+
+	for i := 0; i < numOfSPS; i++ {
+		nalu, n, err := readParameterSet(b[pos:])
+		if err != nil {
+			return nil, err
+		}
+
+		record.SPS = append(record.SPS, nalu)
+		pos += n
+	}
+
+	if pos >= len(b) {
+		return nil, io.ErrUnexpectedEOF
+	}
+
+	numOfPPS := int(b[pos])
+	pos++
+
+	for i := 0; i < numOfPPS; i++ {
+		nalu, n, err := readParameterSet(b[pos:])
+		if err != nil {
+			return nil, err
+		}
+
+		record.PPS = append(record.PPS, nalu)
+		pos += n
+	}
+
+	return record, nil
+}
+
+// readParameterSet reads a 16-bit length prefixed parameter set NAL unit,
+// returns the NAL unit and number of bytes read.
+func readParameterSet(b []byte) ([]byte, int, error) {
+	if len(b) < 2 {
+		return nil, 0, io.ErrUnexpectedEOF
+	}
+
+	length := int(bin.U16BE(b[:2]))
+	if len(b) < 2+length {
+		return nil, 0, io.ErrUnexpectedEOF
+	}
+
+	return b[2 : 2+length], 2 + length, nil
+}
+
+// Info is the stream information derived from AVC sequence header.
+type Info struct {
+	Profile        uint8
+	Level          uint8
+	ChromaFormat   uint32 // 0: monochrome, 1: 4:2:0, 2: 4:2:2, 3: 4:4:4
+	Width          int
+	Height         int
+	FrameRate      float64 // 0 if frame rate is not signalled in VUI
+	NALULengthSize int
+}
+
+// ParseInfo parses AVC sequence header and its first SPS into stream information.
+func ParseInfo(sequenceHeader []byte) (*Info, error) {
+	record, err := ParseAVCDecoderConfigurationRecord(sequenceHeader)
+	if err != nil {
+		return nil, err
+	}
+
+	if len(record.SPS) == 0 {
+		return nil, ErrInvalidConfig
+	}
+
+	sps, err := ParseSPS(record.SPS[0])
+	if err != nil {
+		return nil, err
+	}
+
+	return &Info{
+		Profile:        sps.ProfileIDC,
+		Level:          sps.LevelIDC,
+		ChromaFormat:   sps.ChromaFormatIDC,
+		Width:          sps.Width,
+		Height:         sps.Height,
+		FrameRate:      sps.FrameRate,
+		NALULengthSize: record.NALULengthSize,
+	}, nil
+}
@@ -0,0 +1,97 @@
+package h264
+
+import (
+	"bytes"
+	"io"
+	"testing"
+)
+
+// PPS NAL unit of x264, High profile
+var ppsHigh = mustDecodeHex("68ebe3cb22c0")
+
+// avcc returns AVCDecoderConfigurationRecord of sps and pps, with lengthSizeMinusOne byte of lengthSize.
+func avcc(lengthSize byte, sps []byte, pps []byte) []byte {
+	b := []byte{0x01, sps[1], sps[2], sps[3], lengthSize, 0xe1, byte(len(sps) >> 8), byte(len(sps))}
+	b = append(b, sps...)
+	b = append(b, 0x01, byte(len(pps)>>8), byte(len(pps)))
+	return append(b, pps...)
+}
+
+func TestParseAVCDecoderConfigurationRecord(t *testing.T) {
+	tests := []struct {
+		name       string
+		record     []byte
+		lengthSize int
+	}{
+		{"4-byte length", avcc(0xff, spsHigh720p, ppsHigh), 4},
+		{"2-byte length", avcc(0xfd, spsHigh720p, ppsHigh), 2},
+		{"1-byte length", avcc(0xfc, spsHigh720p, ppsHigh), 1},
+	}
+
+	for _, test := range tests {
+		record, err := ParseAVCDecoderConfigurationRecord(test.record)
+		if err != nil {
+			t.Errorf("%s: ParseAVCDecoderConfigurationRecord() = %v", test.name, err)
+			continue
+		}
+
+		if record.ProfileIndication != 100 || record.ProfileCompatibility != 0 || record.LevelIndication != 31 {
+			t.Errorf("%s: profile %d, compatibility %d, level %d, want 100, 0, 31", test.name,
+				record.ProfileIndication, record.ProfileCompatibility, record.LevelIndication)
+		}
+
+		if record.NALULengthSize != test.lengthSize {
+			t.Errorf("%s: NAL unit length size = %d, want %d", test.name, record.NALULengthSize, test.lengthSize)
+		}
+
+		if len(record.SPS) != 1 || !bytes.Equal(record.SPS[0], spsHigh720p) ||
+			len(record.PPS) != 1 || !bytes.Equal(record.PPS[0], ppsHigh) {
+			t.Errorf("%s: SPS % x, PPS % x", test.name, record.SPS, record.PPS)
+		}
+	}
+}
+
+func TestParseAVCDecoderConfigurationRecordInvalid(t *testing.T) {
+	valid := avcc(0xff, spsHigh720p, ppsHigh)
+
+	invalidVersion := append([]byte{}, valid...)
+	invalidVersion[0] = 0
+
+	tests := []struct {
+		name   string
+		record []byte
+		err    error
+	}{
+		{"empty", nil, io.ErrUnexpectedEOF},
+		{"header only", valid[:6], io.ErrUnexpectedEOF},
+		{"truncated SPS length", valid[:7], io.ErrUnexpectedEOF},
+		{"truncated SPS", valid[:20], io.ErrUnexpectedEOF},
+		{"missing PPS count", valid[:8+len(spsHigh720p)], io.ErrUnexpectedEOF},
+		{"truncated PPS", valid[:len(valid)-1], io.ErrUnexpectedEOF},
+		{"invalid version", invalidVersion, ErrInvalidConfig},
+		{"3-byte length", avcc(0xfe, spsHigh720p, ppsHigh), ErrInvalidConfig},
+	}
+
+	for _, test := range tests {
+		if _, err := ParseAVCDecoderConfigurationRecord(test.record); err != test.err {
+			t.Errorf("%s: ParseAVCDecoderConfigurationRecord() = %v, want %v", test.name, err, test.err)
+		}
+	}
+}
+
+func TestParseInfo(t *testing.T) {
+	info, err := ParseInfo(avcc(0xff, spsHigh1080p, ppsHigh))
+	if err != nil {
+		t.Fatalf("ParseInfo() = %v", err)
+	}
+
+	want := Info{Profile: 100, Level: 40, ChromaFormat: 1, Width: 1920, Height: 1080, FrameRate: 30, NALULengthSize: 4}
+	if *info != want {
+		t.Errorf("ParseInfo() = %+v, want %+v", *info, want)
+	}
+
+	// Sequence header without SPS
+	if _, err := ParseInfo([]byte{0x01, 0x64, 0x00, 0x1f, 0xff, 0xe0, 0x00}); err != ErrInvalidConfig {
+		t.Errorf("ParseInfo() without SPS = %v, want %v", err, ErrInvalidConfig)
+	}
+}
@@ -0,0 +1,239 @@
+package h264
+
+import (
+	"errors"
+
+	bin "github.com/frankchang0125/go-live-stream/binary"
+)
+
+// NALUType is the type of NAL unit defined in ITU-T H.264 Table 7-1.
+type NALUType uint8
+
+const (
+	NALUTypeNonIDR NALUType = 1
+	NALUTypeIDR    NALUType = 5
+	NALUTypeSEI    NALUType = 6
+	NALUTypeSPS    NALUType = 7
+	NALUTypePPS    NALUType = 8
+	NALUTypeAUD    NALUType = 9
+)
+
+var (
+	ErrInvalidNALULengthSize = errors.New("Invalid NAL unit length size")
+	ErrNALUTooLarge          = errors.New("NAL unit too large for length size")
+)
+
+// startCode is prepended to each NAL unit in Annex-B byte stream.
+var startCode = []byte{0, 0, 0, 1}
+
+func (t NALUType) String() string {
+	switch t {
+	case NALUTypeNonIDR:
+		return "non-IDR"
+	case NALUTypeIDR:
+		return "IDR"
+	case NALUTypeSEI:
+		return "SEI"
+	case NALUTypeSPS:
+		return "SPS"
+	case NALUTypePPS:
+		return "PPS"
+	case NALUTypeAUD:
+		return "AUD"
+	}
+
+	return "other"
+}
+
+// TypeOf returns the type of NAL unit, 0 if NAL unit is empty.
+func TypeOf(nalu []byte) NALUType {
+	if len(nalu) == 0 {
+		return 0
+	}
+
+	return NALUType(nalu[0] & 0x1f)
+}
+
+// SplitAVCC splits AVCC coded frame, i.e. NAL units prefixed with
+// lengthSize bytes of length, into NAL units.
+func SplitAVCC(data []byte, lengthSize int) ([][]byte, error) {
+	r := bin.NewReader(data)
+	var nalus [][]byte
+
+	for r.Len() > 0 {
+		var length uint32
+		var err error
+
+		switch lengthSize {
+		case 1:
+			var u8 uint8
+			u8, err = r.U8()
+			length = uint32(u8)
+		case 2:
+			var u16 uint16
+			u16, err = r.U16BE()
+			length = uint32(u16)
+		case 4:
+			length, err = r.U32BE()
+		default:
+			return nil, ErrInvalidNALULengthSize
+		}
+
+		if err != nil {
+			return nil, err
+		}
+
+		nalu, err := r.Bytes(int(length))
+		if err != nil {
+			return nil, err
+		}
+
+		nalus = append(nalus, nalu)
+	}
+
+	return nalus, nil
+}
+
+// JoinAVCC joins NAL units into AVCC coded frame with lengthSize bytes of length prefix.
+func JoinAVCC(nalus [][]byte, lengthSize int) ([]byte, error) {
+	size := 0
+	for _, nalu := range nalus {
+		size += lengthSize + len(nalu)
+	}
+
+	w := bin.NewWriter(size)
+
+	for _, nalu := range nalus {
+		length := uint64(len(nalu))
+
+		switch lengthSize {
+		case 1, 2:
+			if length >= 1<<(8*uint(lengthSize)) {
+				return nil, ErrNALUTooLarge
+			}
+
+			if lengthSize == 1 {
+				w.PutU8(uint8(length))
+			} else {
+				w.PutU16BE(uint16(length))
+			}
+		case 4:
+			if length >= 1<<32 {
+				return nil, ErrNALUTooLarge
+			}
+
+			w.PutU32BE(uint32(length))
+		default:
+			return nil, ErrInvalidNALULengthSize
+		}
+
+		w.PutBytes(nalu)
+	}
+
+	return w.Bytes(), nil
+}
+
+// SplitAnnexB splits Annex-B byte stream into NAL units,
+// both 3-byte and 4-byte start codes are recognized.
+func SplitAnnexB(data []byte) [][]byte {
+	var nalus [][]byte
+	start := -1
+
+	for i := 0; i+2 < len(data); {
+		if data[i] != 0 || data[i+1] != 0 || data[i+2] != 1 {
+			i++
+			continue
+		}
+
+		if start >= 0 {
+			nalus = appendNALU(nalus, data[start:i])
+		}
+
+		i += 3
+		start = i
+	}
+
+	if start >= 0 {
+		nalus = appendNALU(nalus, data[start:])
+	}
+
+	return nalus
+}
+
+// appendNALU appends NAL unit stripped of trailing zero bytes,
+// which belong to the next 4-byte start code or are trailing_zero_8bits.
+func appendNALU(nalus [][]byte, nalu []byte) [][]byte {
+	for len(nalu) > 0 && nalu[len(nalu)-1] == 0 {
+		nalu = nalu[:len(nalu)-1]
+	}
+
+	if len(nalu) == 0 {
+		return nalus
+	}
+
+	return append(nalus, nalu)
+}
+
+// JoinAnnexB joins NAL units into Annex-B byte stream with 4-byte start codes.
+func JoinAnnexB(nalus [][]byte) []byte {
+	size := 0
+	for _, nalu := range nalus {
+		size += len(startCode) + len(nalu)
+	}
+
+	w := bin.NewWriter(size)
+
+	for _, nalu := range nalus {
+		w.PutBytes(startCode)
+		w.PutBytes(nalu)
+	}
+
+	return w.Bytes()
+}
+
+// AVCCToAnnexB converts AVCC coded frame to Annex-B byte stream.
+func AVCCToAnnexB(data []byte, lengthSize int) ([]byte, error) {
+	nalus, err := SplitAVCC(data, lengthSize)
+	if err != nil {
+		return nil, err
+	}
+
+	return JoinAnnexB(nalus), nil
+}
+
+// AnnexBToAVCC converts Annex-B byte stream to AVCC coded frame.
+func AnnexBToAVCC(data []byte, lengthSize int) ([]byte, error) {
+	return JoinAVCC(SplitAnnexB(data), lengthSize)
+}
+
+// NALUTypes returns types of NAL units in AVCC coded frame.
+func NALUTypes(data []byte, lengthSize int) ([]NALUType, error) {
+	nalus, err := SplitAVCC(data, lengthSize)
+	if err != nil {
+		return nil, err
+	}
+
+	types := make([]NALUType, 0, len(nalus))
+	for _, nalu := range nalus {
+		types = append(types, TypeOf(nalu))
+	}
+
+	return types, nil
+}
+
+// HasIDR reports whether AVCC coded frame contains an IDR NAL unit,
+// i.e. whether the frame is truly a key frame.
+func HasIDR(data []byte, lengthSize int) (bool, error) {
+	types, err := NALUTypes(data, lengthSize)
+	if err != nil {
+		return false, err
+	}
+
+	for _, t := range types {
+		if t == NALUTypeIDR {
+			return true, nil
+		}
+	}
+
+	return false, nil
+}
@@ -0,0 +1,160 @@
+package h264
+
+import (
+	"bytes"
+	"io"
+	"reflect"
+	"testing"
+)
+
+var (
+	naluAUD = []byte{0x09, 0xf0}
+	naluSEI = []byte{0x06, 0x05, 0x01, 0x00, 0x80}
+	naluIDR = []byte{0x65, 0x88, 0x84, 0x00, 0x33}
+	naluP   = []byte{0x41, 0x9a, 0x02, 0x04}
+)
+
+func TestSplitAVCC(t *testing.T) {
+	tests := []struct {
+		name       string
+		data       []byte
+		lengthSize int
+		nalus      [][]byte
+	}{
+		{"1-byte length", []byte{0x02, 0x09, 0xf0, 0x05, 0x65, 0x88, 0x84, 0x00, 0x33}, 1, [][]byte{naluAUD, naluIDR}},
+		{"2-byte length", []byte{0x00, 0x02, 0x09, 0xf0, 0x00, 0x05, 0x65, 0x88, 0x84, 0x00, 0x33}, 2, [][]byte{naluAUD, naluIDR}},
+		{"4-byte length", []byte{0x00, 0x00, 0x00, 0x04, 0x41, 0x9a, 0x02, 0x04}, 4, [][]byte{naluP}},
+		{"empty NAL unit", []byte{0x00, 0x00, 0x00, 0x00}, 4, [][]byte{{}}},
+		{"empty frame", nil, 4, nil},
+	}
+
+	for _, test := range tests {
+		nalus, err := SplitAVCC(test.data, test.lengthSize)
+		if err != nil || !reflect.DeepEqual(nalus, test.nalus) {
+			t.Errorf("%s: SplitAVCC() = % x, %v, want % x", test.name, nalus, err, test.nalus)
+		}
+
+		// Round trip
+		data, err := JoinAVCC(test.nalus, test.lengthSize)
+		if err != nil || !bytes.Equal(data, test.data) {
+			t.Errorf("%s: JoinAVCC() = % x, %v, want % x", test.name, data, err, test.data)
+		}
+	}
+}
+
+func TestSplitAVCCInvalid(t *testing.T) {
+	tests := []struct {
+		name       string
+		data       []byte
+		lengthSize int
+		err        error
+	}{
+		{"truncated 2-byte length", []byte{0x00}, 2, io.ErrUnexpectedEOF},
+		{"truncated 4-byte length", []byte{0x00, 0x00, 0x00}, 4, io.ErrUnexpectedEOF},
+		{"truncated length after NAL unit", []byte{0x00, 0x02, 0x09, 0xf0, 0x00}, 2, io.ErrUnexpectedEOF},
+		{"length beyond frame", []byte{0x00, 0x00, 0x00, 0x05, 0x65, 0x88}, 4, io.ErrUnexpectedEOF},
+		{"huge length", []byte{0xff, 0xff, 0xff, 0xff, 0x65}, 4, io.ErrUnexpectedEOF},
+		{"3-byte length", []byte{0x00, 0x00, 0x02, 0x09, 0xf0}, 3, ErrInvalidNALULengthSize},
+	}
+
+	for _, test := range tests {
+		if _, err := SplitAVCC(test.data, test.lengthSize); err != test.err {
+			t.Errorf("%s: SplitAVCC() = %v, want %v", test.name, err, test.err)
+		}
+	}
+}
+
+func TestJoinAVCCInvalid(t *testing.T) {
+	if _, err := JoinAVCC([][]byte{make([]byte, 256)}, 1); err != ErrNALUTooLarge {
+		t.Errorf("JoinAVCC() of 256 bytes with 1-byte length = %v, want %v", err, ErrNALUTooLarge)
+	}
+
+	if _, err := JoinAVCC([][]byte{make([]byte, 65536)}, 2); err != ErrNALUTooLarge {
+		t.Errorf("JoinAVCC() of 65536 bytes with 2-byte length = %v, want %v", err, ErrNALUTooLarge)
+	}
+
+	if _, err := JoinAVCC([][]byte{naluIDR}, 3); err != ErrInvalidNALULengthSize {
+		t.Errorf("JoinAVCC() with 3-byte length = %v, want %v", err, ErrInvalidNALULengthSize)
+	}
+}
+
+func TestSplitAnnexB(t *testing.T) {
+	tests := []struct {
+		name  string
+		data  []byte
+		nalus [][]byte
+	}{
+		{"4-byte start codes", JoinAnnexB([][]byte{naluAUD, naluSEI, naluIDR}), [][]byte{naluAUD, naluSEI, naluIDR}},
+		{"3-byte start codes", []byte{0x00, 0x00, 0x01, 0x09, 0xf0, 0x00, 0x00, 0x01, 0x41, 0x9a, 0x02, 0x04}, [][]byte{naluAUD, naluP}},
+		{"mixed start codes", []byte{0x00, 0x00, 0x00, 0x01, 0x09, 0xf0, 0x00, 0x00, 0x01, 0x65, 0x88, 0x84, 0x00, 0x33}, [][]byte{naluAUD, naluIDR}},
+		{"trailing zero bytes", []byte{0x00, 0x00, 0x01, 0x41, 0x9a, 0x02, 0x04, 0x00, 0x00}, [][]byte{naluP}},
+		{"leading bytes before start code", []byte{0xff, 0x00, 0x00, 0x01, 0x09, 0xf0}, [][]byte{naluAUD}},
+		{"empty NAL units", []byte{0x00, 0x00, 0x01, 0x00, 0x00, 0x01, 0x09, 0xf0, 0x00, 0x00, 0x01}, [][]byte{naluAUD}},
+		{"no start code", []byte{0x09, 0xf0}, nil},
+		{"truncated start code", []byte{0x00, 0x00}, nil},
+	}
+
+	for _, test := range tests {
+		if nalus := SplitAnnexB(test.data); !reflect.DeepEqual(nalus, test.nalus) {
+			t.Errorf("%s: SplitAnnexB() = % x, want % x", test.name, nalus, test.nalus)
+		}
+	}
+}
+
+func TestAnnexBAVCCConversion(t *testing.T) {
+	nalus := [][]byte{naluAUD, naluSEI, naluIDR}
+
+	for _, lengthSize := range []int{1, 2, 4} {
+		avcc, err := JoinAVCC(nalus, lengthSize)
+		if err != nil {
+			t.Fatalf("JoinAVCC() = %v", err)
+		}
+
+		annexB, err := AVCCToAnnexB(avcc, lengthSize)
+		if err != nil || !bytes.Equal(annexB, JoinAnnexB(nalus)) {
+			t.Errorf("AVCCToAnnexB() with %d-byte length = % x, %v", lengthSize, annexB, err)
+		}
+
+		back, err := AnnexBToAVCC(annexB, lengthSize)
+		if err != nil || !bytes.Equal(back, avcc) {
+			t.Errorf("AnnexBToAVCC() with %d-byte length = % x, %v, want % x", lengthSize, back, err, avcc)
+		}
+	}
+}
+
+func TestHasIDR(t *testing.T) {
+	tests := []struct {
+		name  string
+		nalus [][]byte
+		idr   bool
+	}{
+		{"IDR after AUD and SEI", [][]byte{naluAUD, naluSEI, naluIDR}, true},
+		{"non-IDR", [][]byte{naluAUD, naluP}, false},
+		{"parameter sets only", [][]byte{spsHigh720p, ppsHigh}, false},
+		{"empty frame", nil, false},
+	}
+
+	for _, test := range tests {
+		for _, lengthSize := range []int{1, 2, 4} {
+			avcc, _ := JoinAVCC(test.nalus, lengthSize)
+
+			idr, err := HasIDR(avcc, lengthSize)
+			if err != nil || idr != test.idr {
+				t.Errorf("%s: HasIDR() with %d-byte length = %v, %v, want %v", test.name, lengthSize, idr, err, test.idr)
+			}
+		}
+	}
+
+	// Truncated length prefix
+	if _, err := HasIDR([]byte{0x00, 0x00, 0x00, 0x05, 0x65, 0x88, 0x84, 0x00, 0x33, 0x00, 0x00}, 4); err != io.ErrUnexpectedEOF {
+		t.Errorf("HasIDR() of truncated length = %v, want %v", err, io.ErrUnexpectedEOF)
+	}
+
+	if types, err := NALUTypes(JoinAnnexB(nil), 4); err != nil || len(types) != 0 {
+		t.Errorf("NALUTypes() of empty frame = %v, %v", types, err)
+	}
+
+	if TypeOf(nil) != 0 || TypeOf(naluIDR) != NALUTypeIDR || TypeOf(naluIDR).String() != "IDR" {
+		t.Errorf("TypeOf() = %v, %v", TypeOf(nil), TypeOf(naluIDR))
+	}
+}
@@ -0,0 +1,256 @@
+package h264
+
+import (
+	"errors"
+
+	bin "github.com/frankchang0125/go-live-stream/binary"
+)
+
+var ErrInvalidSPS = errors.New("Invalid SPS")
+
+// Aspect ratio indicator of extended SAR
+const extendedSAR = 255
+
+// SPS is the sequence parameter set defined in ITU-T H.264 7.3.2.1.1.
+// Only fields needed to describe the stream are kept.
+type SPS struct {
+	ProfileIDC         uint8
+	ConstraintSetFlags uint8
+	LevelIDC           uint8
+	ID                 uint32
+	ChromaFormatIDC    uint32
+	BitDepthLuma       uint32
+	BitDepthChroma     uint32
+	FrameMbsOnly       bool
+	Width              int     // Width in pixels, cropping applied
+	Height             int     // Height in pixels, cropping applied
+	FrameRate          float64 // 0 if timing info is not present in VUI
+}
+
+// Profiles which carry chroma format, bit depth and scaling matrices in SPS
+var highProfiles = map[uint8]bool{
+	100: true, 110: true, 122: true, 244: true, 44: true, 83: true, 86: true,
+	118: true, 128: true, 138: true, 139: true, 134: true, 135: true,
+}
+
+// ParseSPS parses SPS NAL unit, including its 1-byte NAL unit header.
+func ParseSPS(nalu []byte) (*SPS, error) {
+	if len(nalu) < 4 || NALUType(nalu[0]&0x1f) != NALUTypeSPS {
+		return nil, ErrInvalidSPS
+	}
+
+	r := bin.NewBitReader(bin.RemoveEmulationPrevention(nalu[1:]))
+	sps := &SPS{
+		ChromaFormatIDC: 1,
+		BitDepthLuma:    8,
+		BitDepthChroma:  8,
+	}
+
+	// Collect the first error, later reads fail as well
+	var err error
+	readBits := func(n int) uint32 {
+		if err != nil {
+			return 0
+		}
+
+		var v uint32
+		v, err = r.ReadBits(n)
+		return v
+	}
+	readUE := func() uint32 {
+		if err != nil {
+			return 0
+		}
+
+		var v uint32
+		v, err = r.ReadUE()
+		return v
+	}
+	readSE := func() int32 {
+		if err != nil {
+			return 0
+		}
+
+		var v int32
+		v, err = r.ReadSE()
+		return v
+	}
+
+	sps.ProfileIDC = uint8(readBits(8))
+	sps.ConstraintSetFlags = uint8(readBits(8))
+	sps.LevelIDC = uint8(readBits(8))
+	sps.ID = readUE()
+
+	separateColourPlane := false
+
+	if highProfiles[sps.ProfileIDC] {
+		sps.ChromaFormatIDC = readUE()
+		if sps.ChromaFormatIDC == 3 {
+			separateColourPlane = readBits(1) == 1
+		}
+
+		sps.BitDepthLuma = readUE() + 8
+		sps.BitDepthChroma = readUE() + 8
+		readBits(1) // qpprime_y_zero_transform_bypass_flag
+
+		// seq_scaling_matrix_present_flag
+		if readBits(1) == 1 {
+			count := 8
+			if sps.ChromaFormatIDC == 3 {
+				count = 12
+			}
+
+			for i := 0; i < count; i++ {
+				// seq_scaling_list_present_flag
+				if readBits(1) == 0 {
+					continue
+				}
+
+				size := 16
+				if i >= 6 {
+					size = 64
+				}
+
+				lastScale, nextScale := int32(8), int32(8)
+				for j := 0; j < size; j++ {
+					if nextScale != 0 {
+						nextScale = (lastScale + readSE() + 256) % 256
+					}
+
+					if nextScale != 0 {
+						lastScale = nextScale
+					}
+				}
+			}
+		}
+	}
+
+	readUE() // log2_max_frame_num_minus4
+
+	switch picOrderCntType := readUE(); picOrderCntType {
+	case 0:
+		readUE() // log2_max_pic_order_cnt_lsb_minus4
+	case 1:
+		readBits(1) // delta_pic_order_always_zero_flag
+		readSE()    // offset_for_non_ref_pic
+		readSE()    // offset_for_top_to_bottom_field
+
+		numRefFramesInPicOrderCntCycle := readUE()
+		for i := uint32(0); i < numRefFramesInPicOrderCntCycle && err == nil; i++ {
+			readSE() // offset_for_ref_frame
+		}
+	}
+
+	readUE()    // max_num_ref_frames
+	readBits(1) // gaps_in_frame_num_value_allowed_flag
+
+	picWidthInMbs := int(readUE()) + 1
+	picHeightInMapUnits := int(readUE()) + 1
+	sps.FrameMbsOnly = readBits(1) == 1
+
+	if !sps.FrameMbsOnly {
+		readBits(1) // mb_adaptive_frame_field_flag
+	}
+
+	readBits(1) // direct_8x8_inference_flag
+
+	frameHeightFactor := 2
+	if sps.FrameMbsOnly {
+		frameHeightFactor = 1
+	}
+
+	sps.Width = picWidthInMbs * 16
+	sps.Height = frameHeightFactor * picHeightInMapUnits * 16
+
+	// frame_cropping_flag
+	if readBits(1) == 1 {
+		cropLeft := int(readUE())
+		cropRight := int(readUE())
+		cropTop := int(readUE())
+		cropBottom := int(readUE())
+
+		// Crop units, ITU-T H.264 7.4.2.1.1
+		cropUnitX, cropUnitY := 1, frameHeightFactor
+
+		if !separateColourPlane && sps.ChromaFormatIDC != 0 {
+			subWidthC, subHeightC := 2, 2
+			if sps.ChromaFormatIDC == 2 {
+				subHeightC = 1
+			} else if sps.ChromaFormatIDC == 3 {
+				subWidthC, subHeightC = 1, 1
+			}
+
+			cropUnitX = subWidthC
+			cropUnitY = subHeightC * frameHeightFactor
+		}
+
+		sps.Width -= cropUnitX * (cropLeft + cropRight)
+		sps.Height -= cropUnitY * (cropTop + cropBottom)
+	}
+
+	if err != nil {
+		return nil, err
+	}
+
+	if sps.Width <= 0 || sps.Height <= 0 {
+		return nil, ErrInvalidSPS
+	}
+
+	// vui_parameters_present_flag
+	if readBits(1) == 1 {
+		sps.FrameRate = parseVUIFrameRate(readBits, readUE)
+	}
+
+	// VUI is optional for describing the stream, errors in VUI are ignored
+	return sps, nil
+}
+
+// parseVUIFrameRate parses VUI parameters up to timing info, returns 0 if timing info is not present.
+func parseVUIFrameRate(readBits func(int) uint32, readUE func() uint32) float64 {
+	// aspect_ratio_info_present_flag
+	if readBits(1) == 1 {
+		if readBits(8) == extendedSAR {
+			readBits(16) // sar_width
+			readBits(16) // sar_height
+		}
+	}
+
+	// overscan_info_present_flag
+	if readBits(1) == 1 {
+		readBits(1) // overscan_appropriate_flag
+	}
+
+	// video_signal_type_present_flag
+	if readBits(1) == 1 {
+		readBits(3) // video_format
+		readBits(1) // video_full_range_flag
+
+		// colour_description_present_flag
+		if readBits(1) == 1 {
+			readBits(8) // colour_primaries
+			readBits(8) // transfer_characteristics
+			readBits(8) // matrix_coefficients
+		}
+	}
+
+	// chroma_loc_info_present_flag
+	if readBits(1) == 1 {
+		readUE() // chroma_sample_loc_type_top_field
+		readUE() // chroma_sample_loc_type_bottom_field
+	}
+
+	// timing_info_present_flag
+	if readBits(1) == 0 {
+		return 0
+	}
+
+	numUnitsInTick := readBits(32)
+	timeScale := readBits(32)
+
+	if numUnitsInTick == 0 {
+		return 0
+	}
+
+	// Each frame takes 2 ticks (fields)
+	return float64(timeScale) / float64(2*numUnitsInTick)
+}
@@ -0,0 +1,99 @@
+package h264
+
+import (
+	"encoding/hex"
+	"testing"
+)
+
+// SPS NAL units produced by encoders
+var (
+	// x264, High profile, 1280x720, 25 fps
+	spsHigh720p = mustDecodeHex("6764001facd9405005bb011000000300100000030320f1831960")
+	// x264, High profile, 1920x1088 cropped to 1920x1080, 30 fps
+	spsHigh1080p = mustDecodeHex("67640028acd940780227e5c044000003000400000300f03c60c658")
+	// x264, High profile, 1920x1080, 30000/1001 fps
+	spsHigh1080p2997 = mustDecodeHex("67640028acd940780227e5c04400000fa40003a9823c60c658")
+	// Baseline profile, 640x368 cropped to 640x360, 30 fps
+	spsBaseline360p = mustDecodeHex("6742c01ed900a02ff970110000030001000003003c0f162e48")
+	// Main profile, 1280x720, 25 fps
+	spsMain720p = mustDecodeHex("674d401ee8802802dd80b501010140000003004000000c83c58b4480")
+	// Baseline profile, 320x240 without VUI
+	spsBaselineNoVUI = mustDecodeHex("6742c00d8c8d40a0fd00f1022450")
+)
+
+func mustDecodeHex(s string) []byte {
+	b, err := hex.DecodeString(s)
+	if err != nil {
+		panic(err)
+	}
+
+	return b
+}
+
+func TestParseSPS(t *testing.T) {
+	tests := []struct {
+		name      string
+		nalu      []byte
+		profile   uint8
+		level     uint8
+		width     int
+		height    int
+		frameRate float64
+	}{
+		{"high 720p", spsHigh720p, 100, 31, 1280, 720, 25},
+		{"high 1080p cropped", spsHigh1080p, 100, 40, 1920, 1080, 30},
+		{"high 1080p 29.97", spsHigh1080p2997, 100, 40, 1920, 1080, 30000.0 / 1001},
+		{"baseline 360p cropped", spsBaseline360p, 66, 30, 640, 360, 30},
+		{"main 720p", spsMain720p, 77, 30, 1280, 720, 25},
+		{"baseline without VUI", spsBaselineNoVUI, 66, 13, 320, 240, 0},
+	}
+
+	for _, test := range tests {
+		sps, err := ParseSPS(test.nalu)
+		if err != nil {
+			t.Errorf("%s: ParseSPS() = %v", test.name, err)
+			continue
+		}
+
+		if sps.ProfileIDC != test.profile || sps.LevelIDC != test.level {
+			t.Errorf("%s: profile, level = %d, %d, want %d, %d", test.name, sps.ProfileIDC, sps.LevelIDC, test.profile, test.level)
+		}
+
+		if sps.Width != test.width || sps.Height != test.height {
+			t.Errorf("%s: size = %dx%d, want %dx%d", test.name, sps.Width, sps.Height, test.width, test.height)
+		}
+
+		if diff := sps.FrameRate - test.frameRate; diff > 0.001 || diff < -0.001 {
+			t.Errorf("%s: frame rate = %f, want %f", test.name, sps.FrameRate, test.frameRate)
+		}
+
+		if sps.ChromaFormatIDC != 1 || sps.BitDepthLuma != 8 || sps.BitDepthChroma != 8 || !sps.FrameMbsOnly {
+			t.Errorf("%s: chroma format %d, bit depth %d/%d, frame MBs only %v, want 4:2:0 8-bit progressive",
+				test.name, sps.ChromaFormatIDC, sps.BitDepthLuma, sps.BitDepthChroma, sps.FrameMbsOnly)
+		}
+	}
+}
+
+func TestParseSPSInvalid(t *testing.T) {
+	tests := []struct {
+		name string
+		nalu []byte
+	}{
+		{"empty", nil},
+		{"not SPS", mustDecodeHex("68ebe3cb22c0")},
+		{"truncated before size", spsHigh720p[:6]},
+		{"header only", spsHigh720p[:4]},
+	}
+
+	for _, test := range tests {
+		if sps, err := ParseSPS(test.nalu); err == nil {
+			t.Errorf("%s: ParseSPS() = %+v, want error", test.name, sps)
+		}
+	}
+
+	// Truncated VUI is ignored
+	sps, err := ParseSPS(spsHigh720p[:12])
+	if err != nil || sps.Width != 1280 || sps.Height != 720 || sps.FrameRate != 0 {
+		t.Errorf("ParseSPS() of truncated VUI = %+v, %v, want 1280x720 without frame rate", sps, err)
+	}
+}
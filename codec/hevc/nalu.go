@@ -0,0 +1,59 @@
+package hevc
+
+import (
+	"errors"
+	"io"
+)
+
+var ErrInvalidConfig = errors.New("Invalid HEVCDecoderConfigurationRecord")
+
+// NALUType is the type of NAL unit defined in ITU-T H.265 Table 7-1.
+type NALUType uint8
+
+const (
+	NALUTypeBLAWLP    NALUType = 16
+	NALUTypeBLAWRADL  NALUType = 17
+	NALUTypeBLANLP    NALUType = 18
+	NALUTypeIDRWRADL  NALUType = 19
+	NALUTypeIDRNLP    NALUType = 20
+	NALUTypeCRA       NALUType = 21
+	NALUTypeVPS       NALUType = 32
+	NALUTypeSPS       NALUType = 33
+	NALUTypePPS       NALUType = 34
+	NALUTypeAUD       NALUType = 35
+	NALUTypePrefixSEI NALUType = 39
+)
+
+// TypeOf returns the type of NAL unit, 0 if NAL unit is empty.
+func TypeOf(nalu []byte) NALUType {
+	if len(nalu) == 0 {
+		return 0
+	}
+
+	return NALUType((nalu[0] >> 1) & 0x3f)
+}
+
+// IsIRAP reports whether NAL unit type is an intra random access point,
+// i.e. BLA, IDR or CRA picture.
+func (t NALUType) IsIRAP() bool {
+	return t >= NALUTypeBLAWLP && t <= 23
+}
+
+// ParseNALULengthSize returns the size of length prefix of each NAL unit in coded frames
+// from HEVC sequence header, defined in ISO/IEC 14496-15.
+func ParseNALULengthSize(record []byte) (int, error) {
+	if len(record) < 23 {
+		return 0, io.ErrUnexpectedEOF
+	}
+
+	if record[0] != 1 {
+		return 0, ErrInvalidConfig
+	}
+
+	lengthSize := int(record[21]&0x3) + 1
+	if lengthSize == 3 {
+		return 0, ErrInvalidConfig
+	}
+
+	return lengthSize, nil
+}
@@ -0,0 +1,63 @@
+package hevc
+
+import (
+	"io"
+	"testing"
+)
+
+// naluHeader returns the 2-byte NAL unit header of type t.
+func naluHeader(t NALUType) []byte {
+	return []byte{byte(t) << 1, 0x01}
+}
+
+func TestIsIRAP(t *testing.T) {
+	for typ := NALUType(0); typ < 64; typ++ {
+		// BLA_W_LP (16) to RSV_IRAP_VCL23 (23)
+		want := typ >= 16 && typ <= 23
+
+		if got := TypeOf(naluHeader(typ)); got != typ {
+			t.Errorf("TypeOf() of type %d header = %d", typ, got)
+		}
+
+		if irap := typ.IsIRAP(); irap != want {
+			t.Errorf("NALUType(%d).IsIRAP() = %v, want %v", typ, irap, want)
+		}
+	}
+
+	if TypeOf(nil) != 0 {
+		t.Errorf("TypeOf() of empty NAL unit = %d, want 0", TypeOf(nil))
+	}
+}
+
+func TestParseNALULengthSize(t *testing.T) {
+	record := make([]byte, 23)
+	record[0] = 1
+
+	for _, test := range []struct {
+		lengthSizeMinusOne byte
+		lengthSize         int
+		err                error
+	}{
+		{0xff, 4, nil},
+		{0xfd, 2, nil},
+		{0xfc, 1, nil},
+		{0xfe, 0, ErrInvalidConfig},
+	} {
+		record[21] = test.lengthSizeMinusOne
+
+		lengthSize, err := ParseNALULengthSize(record)
+		if lengthSize != test.lengthSize || err != test.err {
+			t.Errorf("ParseNALULengthSize() of %#x = %d, %v, want %d, %v",
+				test.lengthSizeMinusOne, lengthSize, err, test.lengthSize, test.err)
+		}
+	}
+
+	if _, err := ParseNALULengthSize(record[:22]); err != io.ErrUnexpectedEOF {
+		t.Errorf("ParseNALULengthSize() of truncated record = %v, want %v", err, io.ErrUnexpectedEOF)
+	}
+
+	record[0] = 0
+	if _, err := ParseNALULengthSize(record); err != ErrInvalidConfig {
+		t.Errorf("ParseNALULengthSize() of version 0 = %v, want %v", err, ErrInvalidConfig)
+	}
+}
@@ -0,0 +1,124 @@
+package mp3
+
+import (
+	"errors"
+	"io"
+)
+
+var ErrInvalidFrameHeader = errors.New("Invalid MPEG audio frame header")
+
+// MPEG audio versions
+const (
+	Version25 = 0 // MPEG 2.5
+	Version2  = 2 // MPEG 2
+	Version1  = 3 // MPEG 1
+)
+
+// Channel modes
+const (
+	ChannelModeStereo      = 0
+	ChannelModeJointStereo = 1
+	ChannelModeDualChannel = 2
+	ChannelModeMono        = 3
+)
+
+var sampleRates = [4][3]int{
+	Version25: {11025, 12000, 8000},
+	Version2:  {22050, 24000, 16000},
+	Version1:  {44100, 48000, 32000},
+}
+
+// Bitrates in kbps indexed by [MPEG 1 or not][layer - 1][bitrate index]
+var bitrates = [2][3][15]int{
+	{
+		{0, 32, 48, 56, 64, 80, 96, 112, 128, 144, 160, 176, 192, 224, 256},
+		{0, 8, 16, 24, 32, 40, 48, 56, 64, 80, 96, 112, 128, 144, 160},
+		{0, 8, 16, 24, 32, 40, 48, 56, 64, 80, 96, 112, 128, 144, 160},
+	},
+	{
+		{0, 32, 64, 96, 128, 160, 192, 224, 256, 288, 320, 352, 384, 416, 448},
+		{0, 32, 48, 56, 64, 80, 96, 112, 128, 160, 192, 224, 256, 320, 384},
+		{0, 32, 40, 48, 56, 64, 80, 96, 112, 128, 160, 192, 224, 256, 320},
+	},
+}
+
+// FrameHeader is the 4-byte header of MPEG audio frame.
+type FrameHeader struct {
+	Version         uint8
+	Layer           uint8 // 1, 2 or 3
+	Bitrate         int   // Bitrate in bps, 0 if free format
+	SampleRate      int
+	Padding         bool
+	ChannelMode     uint8
+	SamplesPerFrame int
+	FrameLength     int // Length of frame including header, 0 if free format
+}
+
+// ParseFrameHeader parses the header of MPEG audio frame at the beginning of b.
+func ParseFrameHeader(b []byte) (*FrameHeader, error) {
+	if len(b) < 4 {
+		return nil, io.ErrUnexpectedEOF
+	}
+
+	// Frame sync: 11 bits set
+	if b[0] != 0xff || b[1]&0xe0 != 0xe0 {
+		return nil, ErrInvalidFrameHeader
+	}
+
+	version := (b[1] >> 3) & 0x3
+	layerBits := (b[1] >> 1) & 0x3
+	bitrateIndex := b[2] >> 4
+	sampleRateIndex := (b[2] >> 2) & 0x3
+
+	if version == 1 || layerBits == 0 || bitrateIndex == 0xf || sampleRateIndex == 0x3 {
+		return nil, ErrInvalidFrameHeader
+	}
+
+	header := &FrameHeader{
+		Version:     version,
+		Layer:       4 - layerBits,
+		SampleRate:  sampleRates[version][sampleRateIndex],
+		Padding:     b[2]&0x2 != 0,
+		ChannelMode: b[3] >> 6,
+	}
+
+	mpeg1 := 0
+	if version == Version1 {
+		mpeg1 = 1
+	}
+
+	header.Bitrate = bitrates[mpeg1][header.Layer-1][bitrateIndex] * 1000
+
+	switch {
+	case header.Layer == 1:
+		header.SamplesPerFrame = 384
+	case header.Layer == 3 && version != Version1:
+		header.SamplesPerFrame = 576
+	default:
+		header.SamplesPerFrame = 1152
+	}
+
+	if header.Bitrate > 0 {
+		padding := 0
+		if header.Padding {
+			padding = 1
+		}
+
+		if header.Layer == 1 {
+			header.FrameLength = (12*header.Bitrate/header.SampleRate + padding) * 4
+		} else {
+			header.FrameLength = header.SamplesPerFrame/8*header.Bitrate/header.SampleRate + padding
+		}
+	}
+
+	return header, nil
+}
+
+// Channels returns the number of channels.
+func (header *FrameHeader) Channels() int {
+	if header.ChannelMode == ChannelModeMono {
+		return 1
+	}
+
+	return 2
+}
@@ -0,0 +1,155 @@
+package opus
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"time"
+
+	bin "github.com/frankchang0125/go-live-stream/binary"
+)
+
+var (
+	ErrInvalidHead   = errors.New("Invalid OpusHead")
+	ErrInvalidPacket = errors.New("Invalid Opus packet")
+)
+
+// SampleRate is the sample rate of Opus timestamps and durations.
+const SampleRate = 48000
+
+var headMagic = []byte("OpusHead")
+
+// Head is the Opus identification header, defined in RFC 7845.
+type Head struct {
+	Version              uint8
+	Channels             uint8
+	PreSkip              uint16 // Samples at 48 kHz to discard from the decoder output
+	InputSampleRate      uint32 // Sample rate of the original input, informational only
+	OutputGain           int16  // Gain in Q7.8 dB to apply to the decoder output
+	ChannelMappingFamily uint8
+	StreamCount          uint8   // 1 if channel mapping family is 0
+	CoupledCount         uint8   // Channels - 1 if channel mapping family is 0
+	ChannelMapping       []uint8 // Empty if channel mapping family is 0
+}
+
+// ParseHead parses OpusHead, which is the sequence header of Opus.
+func ParseHead(b []byte) (*Head, error) {
+	r := bin.NewReader(b)
+
+	magic, err := r.Bytes(len(headMagic))
+	if err != nil {
+		return nil, err
+	}
+
+	if !bytes.Equal(magic, headMagic) {
+		return nil, ErrInvalidHead
+	}
+
+	header, err := r.Bytes(11)
+	if err != nil {
+		return nil, err
+	}
+
+	head := &Head{
+		Version:              header[0],
+		Channels:             header[1],
+		PreSkip:              bin.U16LE(header[2:4]),
+		InputSampleRate:      bin.U32LE(header[4:8]),
+		OutputGain:           bin.I16LE(header[8:10]),
+		ChannelMappingFamily: header[10],
+	}
+
+	// Only major version 0 is defined
+	if head.Version>>4 != 0 || head.Channels == 0 {
+		return nil, ErrInvalidHead
+	}
+
+	if head.ChannelMappingFamily == 0 {
+		if head.Channels > 2 {
+			return nil, ErrInvalidHead
+		}
+
+		head.StreamCount = 1
+		head.CoupledCount = head.Channels - 1
+		return head, nil
+	}
+
+	head.StreamCount, err = r.U8()
+	if err != nil {
+		return nil, err
+	}
+
+	head.CoupledCount, err = r.U8()
+	if err != nil {
+		return nil, err
+	}
+
+	head.ChannelMapping, err = r.Bytes(int(head.Channels))
+	if err != nil {
+		return nil, err
+	}
+
+	if head.StreamCount == 0 || head.CoupledCount > head.StreamCount {
+		return nil, ErrInvalidHead
+	}
+
+	return head, nil
+}
+
+// frameSamples returns the number of samples at 48 kHz per frame of TOC configuration.
+func frameSamples(config uint8) int {
+	switch {
+	case config < 12:
+		// SILK-only: 10, 20, 40, 60 ms
+		return []int{480, 960, 1920, 2880}[config&0x3]
+	case config < 16:
+		// Hybrid: 10, 20 ms
+		return []int{480, 960}[config&0x1]
+	default:
+		// CELT-only: 2.5, 5, 10, 20 ms
+		return []int{120, 240, 480, 960}[config&0x3]
+	}
+}
+
+// PacketSamples returns the number of samples at 48 kHz in Opus packet from its TOC byte,
+// defined in RFC 6716 section 3.1.
+func PacketSamples(packet []byte) (int, error) {
+	if len(packet) == 0 {
+		return 0, io.ErrUnexpectedEOF
+	}
+
+	toc := packet[0]
+	frames := 1
+
+	switch toc & 0x3 {
+	case 0:
+		frames = 1
+	case 1, 2:
+		frames = 2
+	case 3:
+		if len(packet) < 2 {
+			return 0, io.ErrUnexpectedEOF
+		}
+
+		frames = int(packet[1] & 0x3f)
+	}
+
+	samples := frames * frameSamples(toc>>3)
+
+	// Packets longer than 120 ms are invalid
+	if frames == 0 || samples > 5760 {
+		return 0, ErrInvalidPacket
+	}
+
+	return samples, nil
+}
+
+// PacketDuration returns the duration of Opus packet.
+func PacketDuration(packet []byte) (time.Duration, error) {
+	samples, err := PacketSamples(packet)
+	if err != nil {
+		return 0, err
+	}
+
+	return time.Duration(samples) * time.Second / SampleRate, nil
+}
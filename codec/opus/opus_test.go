@@ -0,0 +1,126 @@
+package opus
+
+import (
+	"io"
+	"testing"
+	"time"
+)
+
+// toc returns TOC byte of config, stereo flag and frame count code.
+func toc(config uint8, stereo bool, code uint8) byte {
+	b := config<<3 | code
+	if stereo {
+		b |= 0x4
+	}
+
+	return b
+}
+
+func TestFrameSamplesConfig(t *testing.T) {
+	// Frame durations in 1/10 ms of each configuration, RFC 6716 section 3.1
+	durations := []int{
+		100, 200, 400, 600, // SILK NB
+		100, 200, 400, 600, // SILK MB
+		100, 200, 400, 600, // SILK WB
+		100, 200, // Hybrid SWB
+		100, 200, // Hybrid FB
+		25, 50, 100, 200, // CELT NB
+		25, 50, 100, 200, // CELT WB
+		25, 50, 100, 200, // CELT SWB
+		25, 50, 100, 200, // CELT FB
+	}
+
+	for config, duration := range durations {
+		packet := []byte{toc(uint8(config), config%2 == 0, 0)}
+
+		samples, err := PacketSamples(packet)
+		if err != nil || samples != duration*SampleRate/10000 {
+			t.Errorf("config %d: PacketSamples() = %d, %v, want %d", config, samples, err, duration*SampleRate/10000)
+		}
+	}
+}
+
+func TestPacketSamples(t *testing.T) {
+	tests := []struct {
+		name    string
+		packet  []byte
+		samples int
+		err     error
+	}{
+		{"code 0", []byte{toc(1, false, 0), 0xAA}, 960, nil},
+		{"code 1", []byte{toc(1, true, 1), 0xAA, 0xBB}, 1920, nil},
+		{"code 2", []byte{toc(1, false, 2), 1, 0xAA, 0xBB}, 1920, nil},
+		{"code 3 with 1 frame", []byte{toc(16, false, 3), 1}, 120, nil},
+		{"code 3 with 6 frames", []byte{toc(1, false, 3), 6}, 5760, nil},
+		{"code 3 flags ignored", []byte{toc(19, false, 3), 0xC0 | 3}, 2880, nil},
+		{"120 ms of 2.5 ms frames", []byte{toc(16, false, 3), 48}, 5760, nil},
+		{"120 ms of 60 ms frames", []byte{toc(3, false, 1)}, 5760, nil},
+		{"code 3 with 0 frame", []byte{toc(1, false, 3), 0}, 0, ErrInvalidPacket},
+		{"over 120 ms of 2.5 ms frames", []byte{toc(16, false, 3), 49}, 0, ErrInvalidPacket},
+		{"over 120 ms of 60 ms frames", []byte{toc(3, false, 3), 3}, 0, ErrInvalidPacket},
+		{"code 3 with 63 frames", []byte{toc(31, false, 3), 63}, 0, ErrInvalidPacket},
+		{"empty", nil, 0, io.ErrUnexpectedEOF},
+		{"code 3 without frame count", []byte{toc(1, false, 3)}, 0, io.ErrUnexpectedEOF},
+	}
+
+	for _, test := range tests {
+		samples, err := PacketSamples(test.packet)
+		if samples != test.samples || err != test.err {
+			t.Errorf("%s: PacketSamples() = %d, %v, want %d, %v", test.name, samples, err, test.samples, test.err)
+		}
+	}
+}
+
+func TestPacketDuration(t *testing.T) {
+	tests := []struct {
+		packet   []byte
+		duration time.Duration
+	}{
+		{[]byte{toc(16, false, 0)}, 2500 * time.Microsecond},
+		{[]byte{toc(29, false, 0)}, 5 * time.Millisecond},
+		{[]byte{toc(1, false, 0)}, 20 * time.Millisecond},
+		{[]byte{toc(13, true, 2), 0}, 40 * time.Millisecond},
+		{[]byte{toc(3, false, 3), 2}, 120 * time.Millisecond},
+	}
+
+	for _, test := range tests {
+		duration, err := PacketDuration(test.packet)
+		if err != nil || duration != test.duration {
+			t.Errorf("PacketDuration(% x) = %v, %v, want %v", test.packet, duration, err, test.duration)
+		}
+	}
+
+	if _, err := PacketDuration([]byte{toc(1, false, 3), 0}); err != ErrInvalidPacket {
+		t.Errorf("PacketDuration() of 0 frame = %v, want %v", err, ErrInvalidPacket)
+	}
+}
+
+func TestParseHead(t *testing.T) {
+	head := []byte{
+		'O', 'p', 'u', 's', 'H', 'e', 'a', 'd',
+		1, 2, // Version, channels
+		0x38, 0x01, // Pre-skip 312
+		0x80, 0xBB, 0x00, 0x00, // Input sample rate 48000
+		0x00, 0x00, // Output gain
+		0, // Channel mapping family
+	}
+
+	h, err := ParseHead(head)
+	if err != nil {
+		t.Fatalf("ParseHead() = %v", err)
+	}
+
+	if h.Channels != 2 || h.PreSkip != 312 || h.InputSampleRate != 48000 || h.StreamCount != 1 || h.CoupledCount != 1 {
+		t.Errorf("ParseHead() = %+v", h)
+	}
+
+	if _, err := ParseHead(head[:len(head)-1]); err != io.ErrUnexpectedEOF {
+		t.Errorf("ParseHead() of truncated head = %v, want %v", err, io.ErrUnexpectedEOF)
+	}
+
+	invalid := append([]byte{}, head...)
+	invalid[9] = 3
+	if _, err := ParseHead(invalid); err != ErrInvalidHead {
+		t.Errorf("ParseHead() of 3 channels with mapping family 0 = %v, want %v", err, ErrInvalidHead)
+	}
+}
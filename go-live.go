@@ -1,16 +1,33 @@
 package main
 
 import (
+	"crypto/tls"
 	"flag"
 	"net"
+	"net/http"
 	"os"
-	
+	"strings"
+	"time"
+
 	"github.com/frankchang0125/go-live-stream/rtmp"
 	log "github.com/sirupsen/logrus"
 )
 
 var (
 	rtmpAddr = flag.String("rtmp-addr", ":1935", "RTMP server address:port")
+	apps     = flag.String("apps", "", "Comma-separated list of permitted applications of default virtual host, permit all applications if empty")
+	config   = flag.String("config", "", "Path of JSON configuration file")
+
+	rtmpsAddr = flag.String("rtmps-addr", "", "RTMPS server address:port, e.g. :443, RTMPS is disabled if empty")
+	tlsCert   = flag.String("tls-cert", "", "Path of default RTMPS certificate file")
+	tlsKey    = flag.String("tls-key", "", "Path of default RTMPS key file")
+
+	rtmptAddr = flag.String("rtmpt-addr", "", "RTMPT (RTMP tunneled over HTTP) server address:port, e.g. :80, RTMPT is disabled if empty")
+)
+
+const (
+	certReloadInterval = 10 * time.Second
+	maxAcceptDelay     = time.Second // Longest delay before accepting again on temporary errors
 )
 
 func init() {
@@ -24,30 +41,116 @@ func init() {
 
 func main() {
 	log.Info("Starting RTMP server...")
-	
+
+	serverConfig := &rtmp.Config{}
+	if *config != "" {
+		c, err := rtmp.LoadConfig(*config)
+		if err != nil {
+			log.WithField("err", err).Fatal("Cannot load configuration.")
+			os.Exit(1)
+		}
+
+		serverConfig = c
+	}
+
+	if *apps != "" {
+		defaultVHost := serverConfig.DefaultVHostConfig()
+		defaultVHost.Apps = nil
+
+		for _, app := range strings.Split(*apps, ",") {
+			defaultVHost.Apps = append(defaultVHost.Apps, &rtmp.AppConfig{Name: app})
+		}
+	}
+
+	rtmpServer, err := rtmp.NewRTMPServer(serverConfig)
+	if err != nil {
+		log.WithField("err", err).Fatal("Cannot create RTMP server.")
+		os.Exit(1)
+	}
+
 	listener, err := net.Listen("tcp", *rtmpAddr)
 	if err != nil {
 		log.WithField("err", err).Fatal("Cannot start server.")
 		os.Exit(1)
 	}
 	defer listener.Close()
-	
-	rtmpServer := rtmp.NewRTMPServer()
+
+	if *rtmpsAddr != "" {
+		certStore, err := rtmp.NewCertStore(serverConfig, *tlsCert, *tlsKey)
+		if err != nil {
+			log.WithField("err", err).Fatal("Cannot load TLS certificates.")
+			os.Exit(1)
+		}
+
+		tlsListener, err := tls.Listen("tcp", *rtmpsAddr, certStore.TLSConfig())
+		if err != nil {
+			log.WithField("err", err).Fatal("Cannot start RTMPS server.")
+			os.Exit(1)
+		}
+		defer tlsListener.Close()
+
+		// Reload certificates on change
+		go certStore.Watch(certReloadInterval)
+
+		log.Info("RTMPS server started, waiting for connections.")
+		go func() {
+			err := serve(tlsListener, rtmpServer)
+			log.WithField("err", err).Error("RTMPS server stopped.")
+		}()
+	}
+
+	if *rtmptAddr != "" {
+		go func() {
+			err := http.ListenAndServe(*rtmptAddr, rtmp.NewRTMPTHandler(rtmpServer))
+			if err != nil {
+				log.WithField("err", err).Fatal("Cannot start RTMPT server.")
+				os.Exit(1)
+			}
+		}()
+
+		log.Info("RTMPT server started, waiting for connections.")
+	}
+
 	log.Info("RTMP server started, waiting for connections.")
 
 	// Montior incoming new streamers and viewers
 	go rtmpServer.Monitor()
-	
+
+	err = serve(listener, rtmpServer)
+	log.WithField("err", err).Fatal("RTMP server stopped.")
+}
+
+// serve accepts connections until listener fails, temporary errors
+// (e.g. running out of file descriptors) are retried with backoff.
+func serve(listener net.Listener, rtmpServer *rtmp.Server) error {
+	var delay time.Duration
+
 	for {
 		conn, err := listener.Accept()
-		
+
 		if err != nil {
-			log.WithField("err", err).Error("Cannot accept connection.")
-			conn.Close()
-			continue
+			if netErr, ok := err.(net.Error); ok && netErr.Temporary() {
+				if delay == 0 {
+					delay = 5 * time.Millisecond
+				} else if delay *= 2; delay > maxAcceptDelay {
+					delay = maxAcceptDelay
+				}
+
+				log.WithFields(log.Fields{
+					"err":   err,
+					"retry": delay,
+				}).Error("Cannot accept connection.")
+
+				time.Sleep(delay)
+				continue
+			}
+
+			return err
 		}
-		
+
+		delay = 0
+
 		log.Info("Connection accepted.")
-		go rtmpServer.HandleRTMPRequest(conn)	
+		go rtmpServer.HandleRTMPRequest(conn)
 	}
 }
@@ -0,0 +1,146 @@
+package rtmp
+
+import (
+	"errors"
+	"net"
+	"strings"
+	"sync"
+)
+
+// ACL permits or denies client IPs by CIDR rules, deny rules take precedence over allow rules.
+// A nil ACL permits all IPs.
+type ACL struct {
+	allow []*net.IPNet // All IPs are allowed if empty
+	deny  []*net.IPNet
+}
+
+// NewACL parses allow and deny rules, each rule is either a CIDR or a single IP.
+// NewACL returns nil if there are no rules.
+func NewACL(allow []string, deny []string) (*ACL, error) {
+	if len(allow) == 0 && len(deny) == 0 {
+		return nil, nil
+	}
+
+	allowNets, err := parseCIDRs(allow)
+	if err != nil {
+		return nil, err
+	}
+
+	denyNets, err := parseCIDRs(deny)
+	if err != nil {
+		return nil, err
+	}
+
+	return &ACL{
+		allow: allowNets,
+		deny:  denyNets,
+	}, nil
+}
+
+func parseCIDRs(rules []string) ([]*net.IPNet, error) {
+	nets := make([]*net.IPNet, 0, len(rules))
+
+	for _, rule := range rules {
+		if !strings.Contains(rule, "/") {
+			// Single IP
+			ip := net.ParseIP(rule)
+			if ip == nil {
+				return nil, errors.New("Invalid IP: " + rule)
+			}
+
+			if ip.To4() != nil {
+				rule += "/32"
+			} else {
+				rule += "/128"
+			}
+		}
+
+		_, ipNet, err := net.ParseCIDR(rule)
+		if err != nil {
+			return nil, err
+		}
+
+		nets = append(nets, ipNet)
+	}
+
+	return nets, nil
+}
+
+// Permit reports whether ip is permitted by the ACL.
+func (acl *ACL) Permit(ip string) bool {
+	if acl == nil {
+		return true
+	}
+
+	parsed := net.ParseIP(ip)
+	if parsed == nil {
+		return false
+	}
+
+	for _, ipNet := range acl.deny {
+		if ipNet.Contains(parsed) {
+			return false
+		}
+	}
+
+	if len(acl.allow) == 0 {
+		return true
+	}
+
+	for _, ipNet := range acl.allow {
+		if ipNet.Contains(parsed) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// connLimiter caps the number of concurrent connections in total and per source IP.
+type connLimiter struct {
+	lock     sync.Mutex
+	max      int // Unlimited if 0
+	maxPerIP int // Unlimited if 0
+	total    int
+	perIP    map[string]int // Map<IP>Number of connections
+}
+
+func newConnLimiter(max int, maxPerIP int) *connLimiter {
+	return &connLimiter{
+		max:      max,
+		maxPerIP: maxPerIP,
+		perIP:    make(map[string]int),
+	}
+}
+
+// acquire reserves a connection of ip, returns false if any limit is reached.
+func (l *connLimiter) acquire(ip string) bool {
+	l.lock.Lock()
+	defer l.lock.Unlock()
+
+	if l.max > 0 && l.total >= l.max {
+		return false
+	}
+
+	if l.maxPerIP > 0 && l.perIP[ip] >= l.maxPerIP {
+		return false
+	}
+
+	l.total++
+	l.perIP[ip]++
+
+	return true
+}
+
+// release frees a connection of ip reserved by acquire.
+func (l *connLimiter) release(ip string) {
+	l.lock.Lock()
+	defer l.lock.Unlock()
+
+	l.total--
+	l.perIP[ip]--
+
+	if l.perIP[ip] <= 0 {
+		delete(l.perIP, ip)
+	}
+}
@@ -0,0 +1,119 @@
+package rtmp
+
+import (
+	"testing"
+)
+
+func TestACLPermit(t *testing.T) {
+	acl, err := NewACL(
+		[]string{"10.0.0.0/8", "192.168.1.10", "2001:db8::/32"},
+		[]string{"10.1.0.0/16", "2001:db8::1"},
+	)
+	if err != nil {
+		t.Fatalf("NewACL() = %v", err)
+	}
+
+	tests := []struct {
+		ip     string
+		permit bool
+	}{
+		{"10.0.0.1", true},
+		{"10.255.255.255", true},
+		{"10.1.2.3", false},       // Deny rule takes precedence over allow rule
+		{"192.168.1.10", true},    // Single IP
+		{"192.168.1.11", false},   // Not allowed
+		{"2001:db8::2", true},     // IPv6 CIDR
+		{"2001:db8::1", false},    // Denied single IPv6
+		{"2001:db9::1", false},    // Not allowed
+		{"::ffff:10.0.0.1", true}, // IPv4-mapped IPv6
+		{"invalid", false},        // Unparsable IP
+	}
+
+	for _, test := range tests {
+		if permit := acl.Permit(test.ip); permit != test.permit {
+			t.Errorf("Permit(%s) = %v, want %v", test.ip, permit, test.permit)
+		}
+	}
+}
+
+func TestACLDenyOnly(t *testing.T) {
+	acl, err := NewACL(nil, []string{"127.0.0.0/8"})
+	if err != nil {
+		t.Fatalf("NewACL() = %v", err)
+	}
+
+	// All IPs are allowed if no allow rule is set
+	if !acl.Permit("8.8.8.8") || acl.Permit("127.0.0.1") {
+		t.Errorf("Permit(8.8.8.8), Permit(127.0.0.1) = %v, %v, want true, false",
+			acl.Permit("8.8.8.8"), acl.Permit("127.0.0.1"))
+	}
+
+	// No rules permit all IPs
+	acl, err = NewACL(nil, nil)
+	if acl != nil || err != nil {
+		t.Fatalf("NewACL() without rules = %v, %v, want nil, nil", acl, err)
+	}
+
+	if !acl.Permit("127.0.0.1") {
+		t.Error("Permit() of nil ACL = false")
+	}
+}
+
+func TestACLInvalidRules(t *testing.T) {
+	for _, rule := range []string{"10.0.0.0/33", "10.0.0", "host.example.com", "10.0.0.0/abc"} {
+		if _, err := NewACL([]string{rule}, nil); err == nil {
+			t.Errorf("NewACL(%q) succeeded", rule)
+		}
+	}
+}
+
+func TestConnLimiter(t *testing.T) {
+	limiter := newConnLimiter(3, 2)
+
+	// Per IP limit
+	if !limiter.acquire("10.0.0.1") || !limiter.acquire("10.0.0.1") {
+		t.Fatal("acquire() within limits failed")
+	}
+
+	if limiter.acquire("10.0.0.1") {
+		t.Error("acquire() exceeding per IP limit succeeded")
+	}
+
+	// Total limit
+	if !limiter.acquire("10.0.0.2") {
+		t.Fatal("acquire() of another IP failed")
+	}
+
+	if limiter.acquire("10.0.0.3") {
+		t.Error("acquire() exceeding total limit succeeded")
+	}
+
+	if limiter.total != 3 || limiter.perIP["10.0.0.1"] != 2 || limiter.perIP["10.0.0.2"] != 1 {
+		t.Errorf("total = %d, per IP = %v, want 3, map[10.0.0.1:2 10.0.0.2:1]", limiter.total, limiter.perIP)
+	}
+
+	// Released connections are freed for both limits
+	limiter.release("10.0.0.1")
+
+	if !limiter.acquire("10.0.0.3") {
+		t.Error("acquire() after release failed")
+	}
+
+	limiter.release("10.0.0.1")
+	limiter.release("10.0.0.2")
+	limiter.release("10.0.0.3")
+
+	if limiter.total != 0 || len(limiter.perIP) != 0 {
+		t.Errorf("total = %d, per IP = %v after all released, want 0, empty", limiter.total, limiter.perIP)
+	}
+}
+
+func TestConnLimiterUnlimited(t *testing.T) {
+	limiter := newConnLimiter(0, 0)
+
+	for i := 0; i < 100; i++ {
+		if !limiter.acquire("10.0.0.1") {
+			t.Fatalf("acquire() %d of unlimited limiter failed", i)
+		}
+	}
+}
@@ -41,6 +41,8 @@ func decodeAMF0(r *bytes.Reader) (interface{}, error) {
 		return decodeAMF0Object(r)
 	case AMF0ECMAArray:
 		return decodeAMF0ECMAArray(r)
+	case AFM0StrictArray:
+		return decodeAMF0StrictArray(r)
 	case AMF0Null:
 		return nil, nil
 	default:
@@ -132,3 +134,29 @@ func decodeAMF0ECMAArray(r *bytes.Reader) (Object, error) {
 
 	return decodeAMF0Object(r)
 }
+
+func decodeAMF0StrictArray(r *bytes.Reader) ([]interface{}, error) {
+	var count uint32
+	err := binary.Read(r, binary.BigEndian, &count)
+	if err != nil {
+		return nil, err
+	}
+
+	// Each element takes at least 1 byte of type marker
+	if int64(count) > int64(r.Len()) {
+		return nil, errors.New("invalid AMF0 strict array")
+	}
+
+	array := make([]interface{}, 0, count)
+
+	for i := uint32(0); i < count; i++ {
+		value, err := decodeAMF0(r)
+		if err != nil {
+			return nil, err
+		}
+
+		array = append(array, value)
+	}
+
+	return array, nil
+}
@@ -43,6 +43,9 @@ func encodeAMF0(w io.Writer, val interface{}) error {
     // Object
     case reflect.Map:
         return encodeAMF0Object(w, val.(Object))
+    // Strict Array
+    case reflect.Slice:
+        return encodeAMF0StrictArray(w, v)
     default:
         return errors.New("Unsupported AMF type")
     }
@@ -110,6 +113,27 @@ func encodeAMF0Object(w io.Writer, obj Object) error {
     return binary.Write(w, binary.BigEndian, AMF0ObjectEnd)
 }
 
+func encodeAMF0StrictArray(w io.Writer, v reflect.Value) error {
+    err := binary.Write(w, binary.BigEndian, AFM0StrictArray)
+    if err != nil {
+        return err
+    }
+
+    err = binary.Write(w, binary.BigEndian, uint32(v.Len()))
+    if err != nil {
+        return err
+    }
+
+    for i := 0; i < v.Len(); i++ {
+        err = encodeAMF0(w, v.Index(i).Interface())
+        if err != nil {
+            return err
+        }
+    }
+
+    return nil
+}
+
 func encodeAMF0Null(w io.Writer) error {
     return binary.Write(w, binary.BigEndian, AMF0Null)
 }
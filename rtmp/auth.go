@@ -0,0 +1,178 @@
+package rtmp
+
+import (
+	"bufio"
+	"crypto/subtle"
+	"errors"
+	"net"
+	"net/url"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	log "github.com/sirupsen/logrus"
+)
+
+var (
+	ErrBadName      = errors.New("Bad stream name")
+	ErrUnauthorized = errors.New("Unauthorized")
+)
+
+// PublishRequest describes a publish attempt to be authenticated.
+type PublishRequest struct {
+	VHost      string
+	App        string
+	Name       string     // Stream name with query string stripped
+	Params     url.Values // Query string parameters split off the stream name
+	TcURL      string
+	RemoteAddr net.Addr
+}
+
+// PublishAuthenticator decides whether a client is allowed to publish a stream.
+// AuthenticatePublish returns ErrBadName or ErrUnauthorized to reject the request.
+type PublishAuthenticator interface {
+	AuthenticatePublish(req *PublishRequest) error
+}
+
+// KeyFileAuthenticator authenticates publishers against a static key file.
+// Each line of the key file is composed of "<app>/<stream name> <key>",
+// blank lines and lines starting with '#' are ignored.
+// Publishers pass the key as "key" query string parameter of the stream name,
+// e.g. "mylive?key=secret".
+// The key file is reloaded on publish if it has been modified since loaded.
+type KeyFileAuthenticator struct {
+	lock    sync.RWMutex
+	path    string
+	modTime time.Time         // Modification time of the key file loaded
+	keys    map[string]string // Map<App/Stream Name>Key
+}
+
+func NewKeyFileAuthenticator(path string) (*KeyFileAuthenticator, error) {
+	auth := &KeyFileAuthenticator{path: path}
+
+	err := auth.load()
+	if err != nil {
+		return nil, err
+	}
+
+	return auth, nil
+}
+
+// load reads keys from the key file, the keys loaded are kept unchanged on error.
+func (a *KeyFileAuthenticator) load() error {
+	file, err := os.Open(a.path)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+
+	info, err := file.Stat()
+	if err != nil {
+		return err
+	}
+
+	keys := make(map[string]string)
+
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+
+		fields := strings.Fields(line)
+		if len(fields) != 2 {
+			return errors.New("Invalid key file line: " + line)
+		}
+
+		keys[fields[0]] = fields[1]
+	}
+
+	err = scanner.Err()
+	if err != nil {
+		return err
+	}
+
+	a.lock.Lock()
+	a.keys = keys
+	a.modTime = info.ModTime()
+	a.lock.Unlock()
+
+	return nil
+}
+
+// reload reloads the key file if it has been modified since loaded.
+func (a *KeyFileAuthenticator) reload() {
+	info, err := os.Stat(a.path)
+	if err != nil {
+		log.WithFields(log.Fields{
+			"path": a.path,
+			"err":  err,
+		}).Error("Fail to check publish key file, keep keys loaded.")
+		return
+	}
+
+	a.lock.RLock()
+	modified := !info.ModTime().Equal(a.modTime)
+	a.lock.RUnlock()
+
+	if !modified {
+		return
+	}
+
+	err = a.load()
+	if err != nil {
+		log.WithFields(log.Fields{
+			"path": a.path,
+			"err":  err,
+		}).Error("Fail to reload publish key file, keep keys loaded.")
+		return
+	}
+
+	log.WithField("path", a.path).Info("Publish key file reloaded.")
+}
+
+func (a *KeyFileAuthenticator) AuthenticatePublish(req *PublishRequest) error {
+	a.reload()
+
+	a.lock.RLock()
+	key, ok := a.keys[channelKey(req.App, req.Name)]
+	a.lock.RUnlock()
+
+	if !ok {
+		return ErrBadName
+	}
+
+	if subtle.ConstantTimeCompare([]byte(key), []byte(req.Params.Get("key"))) != 1 {
+		return ErrUnauthorized
+	}
+
+	return nil
+}
+
+// statusError is returned when a command is rejected and should be
+// responded with an onStatus error of code.
+type statusError struct {
+	code        string
+	description string
+}
+
+func (e *statusError) Error() string {
+	return e.code + ": " + e.description
+}
+
+// publishStatusError maps error returned by PublishAuthenticator to onStatus error.
+func publishStatusError(err error) *statusError {
+	if err == ErrBadName {
+		return &statusError{
+			code:        "NetStream.Publish.BadName",
+			description: "The stream name is not allowed to be published.",
+		}
+	}
+
+	return &statusError{
+		code:        "NetStream.Publish.Unauthorized",
+		description: "The publisher is not authorized to publish the stream.",
+	}
+}
@@ -0,0 +1,153 @@
+package rtmp
+
+import (
+	"io/ioutil"
+	"net/url"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+// writeKeyFile writes content to the key file with modification time of modTime.
+func writeKeyFile(t *testing.T, path string, content string, modTime time.Time) {
+	err := ioutil.WriteFile(path, []byte(content), 0600)
+	if err != nil {
+		t.Fatalf("WriteFile() = %v", err)
+	}
+
+	err = os.Chtimes(path, modTime, modTime)
+	if err != nil {
+		t.Fatalf("Chtimes() = %v", err)
+	}
+}
+
+func newKeyFileDir(t *testing.T) string {
+	dir, err := ioutil.TempDir("", "keyfile")
+	if err != nil {
+		t.Fatalf("TempDir() = %v", err)
+	}
+
+	return dir
+}
+
+func publishRequest(app string, name string, key string) *PublishRequest {
+	params := url.Values{}
+	if key != "" {
+		params.Set("key", key)
+	}
+
+	return &PublishRequest{App: app, Name: name, Params: params}
+}
+
+func TestKeyFileAuthenticator(t *testing.T) {
+	dir := newKeyFileDir(t)
+	defer os.RemoveAll(dir)
+
+	path := filepath.Join(dir, "keys")
+	writeKeyFile(t, path, "# <app>/<stream name> <key>\n\nlive/mylive s3cr3t\n  live/other   0ther  \n", time.Now())
+
+	auth, err := NewKeyFileAuthenticator(path)
+	if err != nil {
+		t.Fatalf("NewKeyFileAuthenticator() = %v", err)
+	}
+
+	tests := []struct {
+		req *PublishRequest
+		err error
+	}{
+		{publishRequest("live", "mylive", "s3cr3t"), nil},
+		{publishRequest("live", "other", "0ther"), nil},
+		{publishRequest("live", "mylive", "0ther"), ErrUnauthorized},
+		{publishRequest("live", "mylive", ""), ErrUnauthorized},
+		{publishRequest("live", "unknown", "s3cr3t"), ErrBadName},
+		{publishRequest("other", "mylive", "s3cr3t"), ErrBadName},
+	}
+
+	for _, test := range tests {
+		err := auth.AuthenticatePublish(test.req)
+		if err != test.err {
+			t.Errorf("AuthenticatePublish(%s/%s, key=%q) = %v, want %v",
+				test.req.App, test.req.Name, test.req.Params.Get("key"), err, test.err)
+		}
+	}
+}
+
+func TestKeyFileAuthenticatorInvalidFile(t *testing.T) {
+	dir := newKeyFileDir(t)
+	defer os.RemoveAll(dir)
+
+	path := filepath.Join(dir, "keys")
+	writeKeyFile(t, path, "live/mylive s3cr3t extra\n", time.Now())
+
+	if _, err := NewKeyFileAuthenticator(path); err == nil {
+		t.Error("NewKeyFileAuthenticator() of invalid line succeeded")
+	}
+
+	if _, err := NewKeyFileAuthenticator(filepath.Join(dir, "missing")); err == nil {
+		t.Error("NewKeyFileAuthenticator() of missing file succeeded")
+	}
+}
+
+func TestKeyFileAuthenticatorReload(t *testing.T) {
+	dir := newKeyFileDir(t)
+	defer os.RemoveAll(dir)
+
+	modTime := time.Now().Add(-time.Hour)
+	path := filepath.Join(dir, "keys")
+	writeKeyFile(t, path, "live/mylive s3cr3t\n", modTime)
+
+	auth, err := NewKeyFileAuthenticator(path)
+	if err != nil {
+		t.Fatalf("NewKeyFileAuthenticator() = %v", err)
+	}
+
+	// Modified key file is reloaded on publish
+	modTime = modTime.Add(time.Minute)
+	writeKeyFile(t, path, "live/mylive n3w\nlive/added 4dded\n", modTime)
+
+	if err := auth.AuthenticatePublish(publishRequest("live", "mylive", "s3cr3t")); err != ErrUnauthorized {
+		t.Errorf("AuthenticatePublish() with replaced key = %v, want %v", err, ErrUnauthorized)
+	}
+
+	if err := auth.AuthenticatePublish(publishRequest("live", "mylive", "n3w")); err != nil {
+		t.Errorf("AuthenticatePublish() with new key = %v", err)
+	}
+
+	if err := auth.AuthenticatePublish(publishRequest("live", "added", "4dded")); err != nil {
+		t.Errorf("AuthenticatePublish() of added stream = %v", err)
+	}
+
+	// Invalid key file keeps the keys loaded
+	modTime = modTime.Add(time.Minute)
+	writeKeyFile(t, path, "invalid\n", modTime)
+
+	if err := auth.AuthenticatePublish(publishRequest("live", "mylive", "n3w")); err != nil {
+		t.Errorf("AuthenticatePublish() after invalid reload = %v", err)
+	}
+
+	// Removed key file keeps the keys loaded
+	os.Remove(path)
+
+	if err := auth.AuthenticatePublish(publishRequest("live", "added", "4dded")); err != nil {
+		t.Errorf("AuthenticatePublish() after key file removed = %v", err)
+	}
+
+	// Fixed key file is reloaded
+	modTime = modTime.Add(time.Minute)
+	writeKeyFile(t, path, "live/mylive f1xed\n", modTime)
+
+	if err := auth.AuthenticatePublish(publishRequest("live", "added", "4dded")); err != ErrBadName {
+		t.Errorf("AuthenticatePublish() of removed stream = %v, want %v", err, ErrBadName)
+	}
+}
+
+func TestPublishStatusError(t *testing.T) {
+	if code := publishStatusError(ErrBadName).code; code != "NetStream.Publish.BadName" {
+		t.Errorf("publishStatusError(ErrBadName) = %s", code)
+	}
+
+	if code := publishStatusError(ErrUnauthorized).code; code != "NetStream.Publish.Unauthorized" {
+		t.Errorf("publishStatusError(ErrUnauthorized) = %s", code)
+	}
+}
@@ -2,20 +2,34 @@ package rtmp
 
 import (
 	"sync"
+
+	"github.com/frankchang0125/go-live-stream/codec/aac"
+	"github.com/frankchang0125/go-live-stream/codec/h264"
+	"github.com/frankchang0125/go-live-stream/codec/opus"
 )
 
 type Channel struct {
-	lock     *sync.RWMutex
-	name     string
-	streamer *Conn
-	viewers  []*Conn
+	lock      *sync.RWMutex
+	app       string
+	name      string
+	streamer  *Conn
+	viewers   []*Conn
+	videoInfo *h264.Info               // Parsed from the latest AVC sequence header, nil if not received
+	audioInfo *aac.AudioSpecificConfig // Parsed from the latest AAC sequence header, nil if not received
+	opusHead  *opus.Head               // Parsed from the latest Opus sequence header, nil if not received
+	tracks    Tracks                   // Tracks detected from the streamer
+
+	timestamps *timestampNormalizer // Normalizes timestamps of streamers
 }
 
-func NewChannel(name string) *Channel {
+func NewChannel(app string, name string) *Channel {
 	return &Channel{
 		lock:    new(sync.RWMutex),
+		app:     app,
 		name:    name,
 		viewers: make([]*Conn, 0),
+
+		timestamps: newTimestampNormalizer(),
 	}
 }
 
@@ -33,3 +47,36 @@ func (ch *Channel) removeViewer(conn *Conn) bool {
 
 	return false
 }
+
+// VideoInfo returns information of the published H.264 video, or nil if unknown.
+func (ch *Channel) VideoInfo() *h264.Info {
+	ch.lock.RLock()
+	defer ch.lock.RUnlock()
+
+	return ch.videoInfo
+}
+
+// AudioInfo returns configuration of the published AAC audio, or nil if unknown.
+func (ch *Channel) AudioInfo() *aac.AudioSpecificConfig {
+	ch.lock.RLock()
+	defer ch.lock.RUnlock()
+
+	return ch.audioInfo
+}
+
+// OpusHead returns identification header of the published Opus audio, or nil if unknown.
+func (ch *Channel) OpusHead() *opus.Head {
+	ch.lock.RLock()
+	defer ch.lock.RUnlock()
+
+	return ch.opusHead
+}
+
+// Tracks returns tracks carried by the published stream,
+// Tracks.Detected is false while tracks are being detected.
+func (ch *Channel) Tracks() Tracks {
+	ch.lock.RLock()
+	defer ch.lock.RUnlock()
+
+	return ch.tracks
+}
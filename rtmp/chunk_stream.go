@@ -2,8 +2,8 @@ package rtmp
 
 import (
 	"io"
-	"encoding/binary"
 	"errors"
+	"strconv"
 
 	"github.com/frankchang0125/go-live-stream/rtmp/amf"
 	bin "github.com/frankchang0125/go-live-stream/binary"
@@ -36,13 +36,16 @@ const (
 
 type ChunkStreamStatus struct {
 	chunk			*Chunk // Latest received/sent chunk on the chunk stream
-	timestampDelta	uint32 // Timestamp delta between previous received/sent chunk on the chunk stream
+	timestampDelta	uint32 // Timestamp delta of the latest chunk message header, 0 for type 0 header
+	extended		bool   // Whether the latest chunk message header carries extended timestamp
+	partial			[]byte // Payload received of the message in progress, nil if no message in progress
 }
 
 type ChunkStream struct {
 	conn     				*Conn
 	curRead  				map[uint32]*ChunkStreamStatus // Latest status of receiver side chunk stream
 	curWrite 				map[uint32]*ChunkStreamStatus // Latest status of sender side chunk stream
+	partialBytes			int64                         // Bytes of partial messages buffered on all chunk streams
 }
 
 func NewChunkStream(conn *Conn) *ChunkStream {
@@ -53,169 +56,231 @@ func NewChunkStream(conn *Conn) *ChunkStream {
 	}
 }
 
+// readChunk reads a chunk, and handles the message once all chunks of the message are received.
+// Chunks of messages on different chunk streams may interleave, thus the partial message
+// of each chunk stream is buffered separately.
 func (cs *ChunkStream) readChunk() error {
-	chunk := &Chunk{}
-
-	buf, err := cs.readBytes(1)
+	format, csid, err := cs.readBasicHeader()
 	if err != nil {
 		if err == io.EOF {
 			return err
 		}
 
-		log.WithField("err", err).Error("Error while reading chunks.")
+		log.WithField("err", err).Error("Error while reading chunk basic header.")
 		return err
 	}
 
-	header := bin.U8(buf)
-	format := uint32(header >> 6)
-	csid := uint32(header) & 0x3F
+	cur := cs.curRead[csid]
 
-	switch csid {
-	case 0:
-		// Chunk basic header 2, read 1 more byte to compose CSID
-		buf, err = cs.readBytes(1)
-		if err != nil {
-			log.WithField("err", err).Error("Error while reading chunk basic header.")
-			return err
+	var chunk *Chunk
+
+	if cur != nil && cur.partial != nil && format == 3 {
+		// Continuation chunk of the message in progress,
+		// which carries extended timestamp if the message header carries one
+		if cur.extended {
+			_, err = cs.readExtendedTimestamp()
+			if err != nil {
+				log.WithField("err", err).Error("Error while reading chunk message header.")
+				return err
+			}
 		}
 
-		csid = uint32(bin.U8(buf))
-	case 1:
-		// Chunk basic header 3, read 2 more bytes to compose CSID
-		buf, err = cs.readBytes(2)
+		chunk = cur.chunk
+	} else {
+		if cur != nil && cur.partial != nil {
+			log.WithFields(log.Fields{
+				"CSID":     csid,
+				"received": len(cur.partial),
+				"length":   cur.chunk.Length,
+			}).Warning("New message started before previous message completes, previous message discarded.")
+
+			cs.discardPartial(cur)
+		}
+
+		chunk, err = cs.readMessageHeader(format, csid, cur)
 		if err != nil {
-			log.WithField("err", err).Error("Error while reading chunk basic header.")
 			return err
 		}
 
-		csid = 1<<8 | uint32(bin.U8(buf))
+		cur = cs.curRead[csid]
 	}
 
-	chunk.CSID = csid
+	// Read payload of the chunk, which is at most chunk size set by client
+	n := chunk.Length - uint32(len(cur.partial))
+	if n > cs.conn.clientChunkSize {
+		n = cs.conn.clientChunkSize
+	}
 
-	// Format type
-	switch format {
-	case 0:
-		buf, err = cs.readBytes(11)
-		if err != nil {
-			log.WithField("err", err).Error("Error while reading chunk message header.")
-			return err
-		}
+	buf, err := cs.readChunkBytes(n)
+	if err != nil {
+		log.WithField("err", err).Error("Error while reading chunk payload.")
+		return err
+	}
 
-		timestamp := bin.U24BE(buf[:3])
+	// Payload is buffered as chunks arrive rather than allocated by the declared message length
+	cur.partial = append(cur.partial, buf...)
+	cs.partialBytes += int64(len(buf))
 
-		if timestamp >= 0xFFFFFF {
-			// Read extended timestamp
-			var extTimestamp uint32
-			err = binary.Read(cs.conn, binary.BigEndian, &extTimestamp)
-			if err != nil {
-				log.WithField("err", err).Error("Error while reading chunk message header.")
-				return err
-			}
+	if uint32(len(cur.partial)) < chunk.Length {
+		return nil
+	}
 
-			chunk.Timestamp = extTimestamp
-		} else {
-			chunk.Timestamp = timestamp
+	chunk.Data = cur.partial
+	cs.discardPartial(cur)
+
+	log.WithFields(log.Fields{
+		"CSID":      chunk.CSID,
+		"timestamp": chunk.Timestamp,
+		"typeID":    chunk.TypeID,
+		"length":    chunk.Length,
+	}).Debug("Message received.")
+
+	return cs.handleMessage(chunk)
+}
+
+// readBasicHeader reads chunk basic header of 1, 2 or 3 bytes,
+// returns chunk type and chunk stream ID.
+func (cs *ChunkStream) readBasicHeader() (uint32, uint32, error) {
+	buf, err := cs.readBytes(1)
+	if err != nil {
+		return 0, 0, err
+	}
+
+	header := bin.U8(buf)
+	format := uint32(header >> 6)
+	csid := uint32(header) & 0x3F
+
+	switch csid {
+	case 0:
+		// Chunk basic header 2, CSID is 64 + the 2nd byte
+		buf, err = cs.readChunkBytes(1)
+		if err != nil {
+			return 0, 0, err
 		}
 
-		chunk.Length = bin.U24BE(buf[3:6])
-		chunk.TypeID = uint32(bin.U8(buf[6:7]))
-		chunk.StreamID = bin.U32LE(buf[7:11])
+		csid = 64 + uint32(bin.U8(buf))
 	case 1:
-		buf, err = cs.readBytes(7)
+		// Chunk basic header 3, CSID is 64 + the 2nd byte + the 3rd byte * 256
+		buf, err = cs.readChunkBytes(2)
 		if err != nil {
-			log.WithField("err", err).Error("Error while reading chunk message header.")
-			return err
+			return 0, 0, err
 		}
 
-		timestampDelta := bin.U24BE(buf[:3])
-
-		if cur, ok := cs.curRead[chunk.CSID]; ok {
-			if timestampDelta >= 0xFFFFFF {
-				// Read extended timestamp
-				var extTimestamp uint32
-				err = binary.Read(cs.conn, binary.BigEndian, &extTimestamp)
-				if err != nil {
-					log.WithField("err", err).Error("Error while reading chunk message header.")
-					return err
-				}
+		csid = 64 + uint32(bin.U16LE(buf))
+	}
 
-				chunk.Timestamp = cur.chunk.Timestamp + extTimestamp
-			} else {
-				chunk.Timestamp = cur.chunk.Timestamp + timestampDelta
-			}
+	return format, csid, nil
+}
 
-			// Copy chunk message header information from previous chunk
-			chunk.StreamID = cur.chunk.StreamID
-		} else {
-			err = errors.New("Calculating received chunk's timestamp by timestamp delta, " +
-				"but cannot find previous chunk's timestamp.")
-			log.WithField("err", err).Error("Error while reading chunk message header.")
-			return err
-		}
+// readMessageHeader reads chunk message header of the first chunk of a message,
+// cur is the latest status of the chunk stream, nil if the chunk stream is new.
+func (cs *ChunkStream) readMessageHeader(format uint32, csid uint32, cur *ChunkStreamStatus) (*Chunk, error) {
+	chunk := &Chunk{CSID: csid}
+	var err error
+
+	// Timestamp delta from the previous message on the chunk stream, 0 for type 0 header
+	var timestampDelta uint32
+	var extended bool
+
+	if cur == nil && format != 0 {
+		err = errors.New("Reading chunk message header of type " + strconv.Itoa(int(format)) +
+			", but cannot find correspond previous chunk.")
+		log.WithField("err", err).Error("Error while reading chunk message header.")
+		return nil, err
+	}
 
-		chunk.Length = bin.U24BE(buf[3:6])
-		chunk.TypeID = uint32(bin.U8(buf[6:7]))
-	case 2:
-		buf, err = cs.readBytes(3)
+	if format < 3 {
+		header, err := cs.readMessageHeaderFields(format)
 		if err != nil {
 			log.WithField("err", err).Error("Error while reading chunk message header.")
-			return err
+			return nil, err
 		}
 
-		timestampDelta := bin.U24BE(buf)
+		timestamp := header.timestamp
+		extended = timestamp == 0xFFFFFF
 
-		if cur, ok := cs.curRead[chunk.CSID]; ok {
-			chunk.Timestamp = cur.chunk.Timestamp + timestampDelta
+		if extended {
+			timestamp, err = cs.readExtendedTimestamp()
+			if err != nil {
+				log.WithField("err", err).Error("Error while reading chunk message header.")
+				return nil, err
+			}
+		}
 
-			// Copy chunk message header information from previous chunk
-			chunk.Length = cur.chunk.Length
-			chunk.TypeID = cur.chunk.TypeID
+		switch format {
+		case 0:
+			chunk.Timestamp = timestamp
+			chunk.Length = header.length
+			chunk.TypeID = header.typeID
+			chunk.StreamID = header.streamID
+		case 1:
+			timestampDelta = timestamp
+			chunk.Timestamp = cur.chunk.Timestamp + timestampDelta
+			chunk.Length = header.length
+			chunk.TypeID = header.typeID
 			chunk.StreamID = cur.chunk.StreamID
-		} else {
-			err = errors.New("Calculating received chunk's timestamp by timestamp delta, " +
-				"but cannot find previous chunk's timestamp")
-			log.WithField("err", err).Error("Error while reading chunk message header.")
-			return err
-		}
-	case 3:
-		if cur, ok := cs.curRead[chunk.CSID]; ok {
-			// Copy chunk message header information from previous chunk
-			chunk.Timestamp = cur.chunk.Timestamp
+		case 2:
+			timestampDelta = timestamp
+			chunk.Timestamp = cur.chunk.Timestamp + timestampDelta
 			chunk.Length = cur.chunk.Length
 			chunk.TypeID = cur.chunk.TypeID
 			chunk.StreamID = cur.chunk.StreamID
-		} else {
-			err = errors.New("Reading type 3 Chunk Message Header, but cannot find correspond previous chunk")
-			log.WithField("err", err).Error("Error while reading chunk message header.")
-			return err
 		}
-	}
-
-	// 1. Save the timestamp delta between current chunk and previous chunk on the chunk stream
-	// 2. Update the latest received chunk on chunk stream
-	if cur, ok := cs.curRead[chunk.CSID]; ok {
-		cur.chunk = chunk
-		cur.timestampDelta = chunk.Timestamp - cur.chunk.Timestamp
 	} else {
-		cs.curRead[chunk.CSID] = &ChunkStreamStatus{
-			chunk:	chunk,
-			timestampDelta: 0,
+		// Type 3 carries extended timestamp if the previous header on the chunk stream carries one,
+		// which is the same as the previous one
+		extended = cur.extended
+
+		if extended {
+			_, err = cs.readExtendedTimestamp()
+			if err != nil {
+				log.WithField("err", err).Error("Error while reading chunk message header.")
+				return nil, err
+			}
 		}
+
+		// New message with the same timestamp delta as the previous message
+		timestampDelta = cur.timestampDelta
+		chunk.Timestamp = cur.chunk.Timestamp + timestampDelta
+		chunk.Length = cur.chunk.Length
+		chunk.TypeID = cur.chunk.TypeID
+		chunk.StreamID = cur.chunk.StreamID
 	}
 
-	// FIXME: Remove me
-	log.WithFields(log.Fields{
-		"timestamp": chunk.Timestamp,
-		"typeID": chunk.TypeID,
-		"length": chunk.Length,
-		}).Debug("readChunk()")
+	err = cs.checkMessage(chunk)
+	if err != nil {
+		log.WithFields(log.Fields{
+			"CSID":   chunk.CSID,
+			"typeID": chunk.TypeID,
+			"length": chunk.Length,
+			"err":    err,
+		}).Error("Message rejected.")
+		return nil, err
+	}
+
+	// Update the latest status of chunk stream, with an empty buffer for the message payload
+	cs.curRead[chunk.CSID] = &ChunkStreamStatus{
+		chunk:          chunk,
+		timestampDelta: timestampDelta,
+		extended:       extended,
+		partial:        []byte{},
+	}
+
+	return chunk, nil
+
+}
+
+// handleMessage handles a complete message.
+func (cs *ChunkStream) handleMessage(chunk *Chunk) error {
+	var err error
 
 	switch chunk.TypeID {
+	case typeIDAbortMsg:
+		err = cs.abortMessage(chunk)
 	case typeIDSetChunkSize:
-		err = cs.conn.setChunkSize()
+		err = cs.conn.setChunkSize(chunk)
 	case typeIDWindowAckSize:
-		err = cs.conn.setWindowAckSize()
+		err = cs.conn.setWindowAckSize(chunk)
 	case typeIDusrCtrlMsg:
 		err = cs.conn.handleUsrCtrlMsg(cs, chunk)
 	case typeIDAudioMsg:
@@ -257,34 +322,51 @@ func (cs *ChunkStream) readChunk() error {
 	return nil
 }
 
-func (cs *ChunkStream) writeChunk(chunk *Chunk, chunkSize uint32) error {
-	numOfChunks := uint32(chunk.Length / chunkSize)
 
-	if chunk.Length % chunkSize != 0 {
-		numOfChunks++
+// abortMessage discards the partial message on the chunk stream specified by abort message.
+func (cs *ChunkStream) abortMessage(chunk *Chunk) error {
+	csid, err := bin.NewReader(chunk.Data).U32BE()
+	if err != nil {
+		log.WithField("err", err).Error("Error while handling abort message.")
+		return err
 	}
 
-	remain := chunk.Length
-	var i uint32
+	if cur, ok := cs.curRead[csid]; ok {
+		cs.discardPartial(cur)
+	}
 
-	for i = 0; i < numOfChunks; i++ {
-		chunkHeader, err := cs.createChunkHeader(chunk)
-		if err != nil {
-			return err
-		}
+	return nil
+}
 
-		start := i * chunkSize
-		var end, size uint32
+// discardPartial releases the partial message on the chunk stream.
+func (cs *ChunkStream) discardPartial(cur *ChunkStreamStatus) {
+	cs.partialBytes -= int64(len(cur.partial))
+	cur.partial = nil
+}
 
-		if remain < chunkSize {
-			size = remain
-		} else {
+func (cs *ChunkStream) writeChunk(chunk *Chunk, chunkSize uint32) error {
+	header, err := cs.createChunkHeader(chunk)
+	if err != nil {
+		return err
+	}
+
+	// Continuation chunks of the message carry type 3 header,
+	// along with extended timestamp if the message header carries one
+	status := cs.curWrite[chunk.CSID]
+	continuationHeader := createBasicHeader(3, chunk.CSID)
+	if status.extended {
+		continuationHeader = append(continuationHeader, header[len(header)-4:]...)
+	}
+
+	var start uint32
+
+	for {
+		size := chunk.Length - start
+		if size > chunkSize {
 			size = chunkSize
 		}
 
-		end = start + size
-
-		c := append(chunkHeader, chunk.Data[start:end]...)
+		c := append(header, chunk.Data[start:start+size]...)
 
 		_, err = cs.conn.Write(c)
 		if err != nil {
@@ -292,189 +374,187 @@ func (cs *ChunkStream) writeChunk(chunk *Chunk, chunkSize uint32) error {
 			return err
 		}
 
-		remain -= size
-
-		// 1. Save the timestamp delta between current chunk and previous chunk
-		// 2. Update the latest sent chunk on chunk stream
-		if cur, ok := cs.curWrite[chunk.CSID]; ok {
-			cur.chunk = chunk
-			cur.timestampDelta = chunk.Timestamp - cur.chunk.Timestamp
-		} else {
-			cs.curWrite[chunk.CSID] = &ChunkStreamStatus{
-				chunk: chunk,
-				timestampDelta: 0,
-			}
+		start += size
+		if start >= chunk.Length {
+			return nil
 		}
-	}
 
-	return nil
+		header = continuationHeader
+	}
 }
 
-func (cs *ChunkStream) createChunkHeader(chunk *Chunk) (header []byte, err error) {
-	var timestamp, timestampDelta, extTimestamp uint32
+// createChunkHeader creates the header of the first chunk of message,
+// and updates the latest status of sender side chunk stream.
+func (cs *ChunkStream) createChunkHeader(chunk *Chunk) ([]byte, error) {
+	if chunk.CSID < 2 || chunk.CSID > 65599 {
+		// Invalidate chunk stream ID
+		log.WithField("CSID", chunk.CSID).Error("Invalidate chunk stream ID.")
+		return nil, errors.New("invalidate chunk stream ID")
+	}
+
+	if chunk.Length > 0xFFFFFF {
+		log.WithField("length", chunk.Length).Error("Invalidate chunk length.")
+		return nil, errors.New("invalidate chunk length")
+	}
+
 	var headerType int
+	var timestampDelta uint32
 
-	if cur, ok := cs.curWrite[chunk.CSID]; ok {
+	if cur, ok := cs.curWrite[chunk.CSID]; ok && chunk.StreamID == cur.chunk.StreamID {
 		timestampDelta = chunk.Timestamp - cur.chunk.Timestamp
 
-		if chunk.StreamID == cur.chunk.StreamID {
-			if (chunk.Length == cur.chunk.Length) && (chunk.TypeID == cur.chunk.TypeID) {
-				if timestampDelta == cur.timestampDelta {
-					// Type 3
-					headerType = 3
-				} else {
-					// Type 2
-					headerType = 2
-				}
+		if (chunk.Length == cur.chunk.Length) && (chunk.TypeID == cur.chunk.TypeID) {
+			// Type 3 starts a new message with the same timestamp delta as the previous header,
+			// which is undefined after type 0 header, thus a zero delta is always sent in type 2
+			if timestampDelta == cur.timestampDelta && timestampDelta != 0 {
+				headerType = 3
 			} else {
-				// Type 1
-				headerType = 1
+				headerType = 2
 			}
 		} else {
-			// Type 0
-			headerType = 0
+			headerType = 1
 		}
 	} else {
-		// Type 0
 		headerType = 0
 	}
 
-	switch headerType {
-	case 0:
-		if chunk.Timestamp > 0xFFFFFF {
-			extTimestamp = chunk.Timestamp
-			timestamp = 0xFFFFFFF
+	// Timestamp field of the message header, either timestamp or timestamp delta
+	timestamp := timestampDelta
+	if headerType == 0 {
+		timestamp = chunk.Timestamp
+	}
+
+	extended := timestamp >= 0xFFFFFF
+
+	w := bin.NewWriter(18)
+	w.PutBytes(createBasicHeader(headerType, chunk.CSID))
+
+	if headerType < 3 {
+		if extended {
+			w.PutU24BE(0xFFFFFF)
 		} else {
-			timestamp = chunk.Timestamp
-		}
-	case 1:
-		fallthrough
-	case 2:
-		if timestampDelta > 0xFFFFFF {
-			extTimestamp = timestampDelta
-			timestampDelta = 0xFFFFFFF
+			w.PutU24BE(timestamp)
 		}
 	}
 
-	var basicHeader []byte
+	if headerType < 2 {
+		w.PutU24BE(chunk.Length)
+		w.PutU8(uint8(chunk.TypeID))
+	}
 
-	if chunk.CSID < 64 {
-		// Chunk basic header 1
-		basicHeader = make([]byte, 1)
-		basicHeader[0] = uint8(chunk.CSID)
-		basicHeader[0] |= uint8(headerType << 6)
-	} else if chunk.CSID < 320 {
-		// Chunk basic header 2
-		basicHeader = make([]byte, 2)
-		basicHeader[0] = uint8(headerType << 6)
-		basicHeader[1] = uint8(chunk.CSID - 64)
-	} else if chunk.CSID < 65560 {
-		// Chunk basic header 3
-		basicHeader = make([]byte, 3)
-		basicHeader[0] = uint8(headerType<<6) | 0x3F
-		basicHeader[1] = uint8((chunk.CSID - 64) >> 8)
-		basicHeader[2] = uint8(chunk.CSID - 64)
-	} else {
-		// Invalidate chunk stream ID
-		log.WithField("CSID", chunk.CSID).Error("Invalidate chunk stream ID.")
-		return nil, errors.New("invalidate chunk stream ID")
+	if headerType == 0 {
+		w.PutU32LE(chunk.StreamID)
 	}
 
-	var messageHeader []byte
+	if extended {
+		w.PutU32BE(timestamp)
+	}
 
-	switch headerType {
-	case 0:
-		messageHeader = make([]byte, 11)
-		err := bin.PutUBE(messageHeader[:3], timestamp)
-		if err != nil {
-			log.WithField("timestamp", timestamp).Error("Invalidate chunk timestamp")
-			return nil, err
-		}
+	if headerType == 0 {
+		timestampDelta = 0
+	}
 
-		err = bin.PutUBE(messageHeader[3:6], chunk.Length)
-		if err != nil {
-			log.WithField("length", chunk.Length).Error("Invalidate chunk length.")
-			return nil, err
-		}
+	cs.curWrite[chunk.CSID] = &ChunkStreamStatus{
+		chunk:          chunk,
+		timestampDelta: timestampDelta,
+		extended:       extended,
+	}
 
-		messageHeader[6] = uint8(chunk.TypeID)
+	return w.Bytes(), nil
+}
 
-		err = bin.PutULE(messageHeader[7:], chunk.StreamID)
-		if err != nil {
-			log.WithField("Stream ID", chunk.StreamID).Error("Invalidate chunk Stream ID.")
-			return nil, err
-		}
-	case 1:
-		messageHeader = make([]byte, 7)
-		err := bin.PutUBE(messageHeader[:3], timestampDelta)
-		if err != nil {
-			log.WithField("timestampDelta", timestampDelta).Error("Invalidate chunk timestamp delta.")
-		}
+// createBasicHeader creates chunk basic header of 1, 2 or 3 bytes depending on CSID.
+func createBasicHeader(headerType int, csid uint32) []byte {
+	if csid < 64 {
+		// Chunk basic header 1
+		return []byte{uint8(headerType<<6) | uint8(csid)}
+	} else if csid < 320 {
+		// Chunk basic header 2
+		return []byte{uint8(headerType << 6), uint8(csid - 64)}
+	}
 
-		err = bin.PutUBE(messageHeader[3:6], chunk.Length)
-		if err != nil {
-			log.WithField("length", chunk.Length).Error("Invalidate chunk length.")
-			return nil, err
-		}
+	// Chunk basic header 3, CSID - 64 in little endian
+	return []byte{uint8(headerType<<6) | 0x1, uint8(csid - 64), uint8((csid - 64) >> 8)}
+}
 
-		messageHeader[6] = uint8(chunk.TypeID)
-	case 2:
-		messageHeader = make([]byte, 3)
+func (cs *ChunkStream) readBytes(n uint32) ([]byte, error) {
+	buf := make([]byte, n)
+	_, err := io.ReadFull(cs.conn, buf)
+	return buf, err
+}
 
-		err := bin.PutUBE(messageHeader[:3], timestampDelta)
-		if err != nil {
-			log.WithField("timestampDelta", timestampDelta).Error("Invalidate chunk timestamp delta.")
-		}
-	case 3:
-		messageHeader = make([]byte, 0)
+// readChunkBytes reads n bytes following the first byte of a chunk,
+// where EOF is unexpected as the chunk is incomplete.
+func (cs *ChunkStream) readChunkBytes(n uint32) ([]byte, error) {
+	buf, err := cs.readBytes(n)
+	if err == io.EOF {
+		err = io.ErrUnexpectedEOF
 	}
 
-	var extTimestampHeader []byte
+	return buf, err
+}
 
-	if extTimestamp > 0 {
-		extTimestampHeader = make([]byte, 4)
-	} else {
-		extTimestampHeader = make([]byte, 0)
+// readExtendedTimestamp reads the 4-byte extended timestamp following chunk message header.
+func (cs *ChunkStream) readExtendedTimestamp() (uint32, error) {
+	buf, err := cs.readChunkBytes(4)
+	if err != nil {
+		return 0, err
 	}
 
-	return append(append(basicHeader, messageHeader...), extTimestampHeader...), nil
+	return bin.NewReader(buf).U32BE()
 }
 
-func (cs *ChunkStream) readAMFBody(length uint32, clientChunkSize uint32) ([]byte, error) {
-	var results = make([]byte, 0)
-	var n uint32
-	remain := length
+// chunkMessageHeader holds the fields of chunk message header,
+// fields not carried by the chunk format are left zero.
+type chunkMessageHeader struct {
+	timestamp uint32 // Timestamp of type 0, timestamp delta of type 1 and type 2
+	length    uint32
+	typeID    uint32
+	streamID  uint32
+}
 
-	for {
-		if remain > clientChunkSize {
-			n = clientChunkSize
-		} else {
-			n = remain
-		}
+// messageHeaderLen is the length of chunk message header of each chunk format.
+var messageHeaderLen = [...]uint32{11, 7, 3, 0}
 
-		buf, err := cs.readBytes(n)
-		if err != nil {
-			return nil, nil
-		}
+// readMessageHeaderFields reads chunk message header fields of format 0, 1 or 2.
+func (cs *ChunkStream) readMessageHeaderFields(format uint32) (*chunkMessageHeader, error) {
+	buf, err := cs.readChunkBytes(messageHeaderLen[format])
+	if err != nil {
+		return nil, err
+	}
 
-		results = append(results, buf...)
+	r := bin.NewReader(buf)
+	header := &chunkMessageHeader{}
 
-		remain -= n
-		if remain == 0 {
-			return results, err
-		}
+	header.timestamp, err = r.U24BE()
+	if err != nil {
+		return nil, err
+	}
 
-		// AMF body exceeds client's chunk size and is divided into
-		// serveral chunks with 1-byte chunk header ahead
-		_, err = cs.readBytes(1)
-		if err != nil {
-			return nil, err
-		}
+	if format == 2 {
+		return header, nil
 	}
-}
 
-func (cs *ChunkStream) readBytes(n uint32) ([]byte, error) {
-	buf := make([]byte, n)
-	_, err := cs.conn.Read(buf)
-	return buf, err
+	header.length, err = r.U24BE()
+	if err != nil {
+		return nil, err
+	}
+
+	typeID, err := r.U8()
+	if err != nil {
+		return nil, err
+	}
+
+	header.typeID = uint32(typeID)
+
+	if format == 1 {
+		return header, nil
+	}
+
+	header.streamID, err = r.U32LE()
+	if err != nil {
+		return nil, err
+	}
+
+	return header, nil
 }
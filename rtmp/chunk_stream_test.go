@@ -0,0 +1,385 @@
+package rtmp
+
+import (
+	"bytes"
+	"io"
+	"net"
+	"testing"
+	"time"
+)
+
+// bufferConn is a net.Conn reading back what has been written to it.
+type bufferConn struct {
+	net.Conn
+	buf bytes.Buffer
+}
+
+func (c *bufferConn) Read(b []byte) (int, error)       { return c.buf.Read(b) }
+func (c *bufferConn) Write(b []byte) (int, error)      { return c.buf.Write(b) }
+func (c *bufferConn) SetReadDeadline(time.Time) error  { return nil }
+func (c *bufferConn) SetWriteDeadline(time.Time) error { return nil }
+
+// newTestChunkStream returns a chunk stream reading back chunks written by itself,
+// with chunk size of chunkSize on both sides.
+func newTestChunkStream(t *testing.T, config *Config, chunkSize uint32) (*ChunkStream, *bufferConn) {
+	server, err := NewRTMPServer(config)
+	if err != nil {
+		t.Fatalf("NewRTMPServer() = %v", err)
+	}
+
+	bc := &bufferConn{}
+	conn := NewConn(bc, server)
+	conn.chunkSize = chunkSize
+	conn.clientChunkSize = chunkSize
+
+	return NewChunkStream(conn), bc
+}
+
+// readMessages reads chunks until n messages are completed, returns messages in order of completion.
+func readMessages(t *testing.T, cs *ChunkStream, n int) []*Chunk {
+	var messages []*Chunk
+	seen := make(map[*Chunk]bool)
+
+	for len(messages) < n {
+		if err := cs.readChunk(); err != nil {
+			t.Fatalf("readChunk() = %v", err)
+		}
+
+		for _, cur := range cs.curRead {
+			if cur.partial == nil && cur.chunk.Data != nil && !seen[cur.chunk] {
+				seen[cur.chunk] = true
+				messages = append(messages, cur.chunk)
+			}
+		}
+	}
+
+	return messages
+}
+
+// testPayload returns a payload of n bytes, each of which is seed plus its index.
+func testPayload(seed byte, n int) []byte {
+	payload := make([]byte, n)
+	for i := range payload {
+		payload[i] = seed + byte(i)
+	}
+
+	return payload
+}
+
+func TestChunkTimestampRoundTrip(t *testing.T) {
+	// Shared object messages are not handled, thus reassembled messages are left untouched
+	const typeID = typeISSharedObjectMsgAMF0
+
+	tests := []struct {
+		name       string
+		timestamp  uint32
+		length     int
+		headerType uint8
+		extended   bool
+	}{
+		{"type 0", 0xFFFFF0, 40, 0, false},
+		{"type 2 to 0xFFFFFF", 0xFFFFFF, 40, 2, false},
+		{"type 1 past 24 bits", 0x1000010, 30, 1, false},
+		{"type 3", 0x1000021, 30, 3, false},
+		{"type 2 with extended delta", 0xFFFFFFF0, 30, 2, true},
+		{"type 2 across rollover", 5, 30, 2, false},
+		{"type 1 with extended delta", 0x1000005, 20, 1, true},
+		{"type 3 with extended delta", 0x2000005, 20, 3, true},
+		{"type 3 repeating extended delta", 0x3000005, 20, 3, true},
+		{"zero delta", 0x3000005, 20, 2, false},
+		{"empty message", 0x3000006, 0, 1, false},
+	}
+
+	for _, csid := range []uint32{3, 100, 400} {
+		cs, bc := newTestChunkStream(t, &Config{}, 16)
+
+		for _, test := range tests {
+			chunk := &Chunk{
+				CSID:      csid,
+				Timestamp: test.timestamp,
+				Length:    uint32(test.length),
+				TypeID:    typeID,
+				StreamID:  1,
+				Data:      testPayload(byte(test.timestamp), test.length),
+			}
+
+			if err := cs.writeChunk(chunk, 16); err != nil {
+				t.Fatalf("CSID %d, %s: writeChunk() = %v", csid, test.name, err)
+			}
+
+			if headerType := bc.buf.Bytes()[0] >> 6; headerType != test.headerType {
+				t.Errorf("CSID %d, %s: header type = %d, want %d", csid, test.name, headerType, test.headerType)
+			}
+
+			if extended := cs.curWrite[csid].extended; extended != test.extended {
+				t.Errorf("CSID %d, %s: extended = %v, want %v", csid, test.name, extended, test.extended)
+			}
+
+			got := readMessages(t, cs, 1)[0]
+
+			if got.CSID != csid || got.Timestamp != test.timestamp || got.TypeID != typeID ||
+				got.StreamID != 1 || !bytes.Equal(got.Data, chunk.Data) {
+				t.Errorf("CSID %d, %s: read %+v, want %+v", csid, test.name, got, chunk)
+			}
+
+			if bc.buf.Len() != 0 {
+				t.Errorf("CSID %d, %s: %d bytes left unread", csid, test.name, bc.buf.Len())
+			}
+		}
+	}
+}
+
+func TestChunkExtendedTimestampType0(t *testing.T) {
+	for _, timestamp := range []uint32{0xFFFFFF, 0x1000010, 0xFFFFFFF0} {
+		cs, bc := newTestChunkStream(t, &Config{}, 128)
+
+		chunk := &Chunk{
+			CSID:      400,
+			Timestamp: timestamp,
+			Length:    300,
+			TypeID:    typeISSharedObjectMsgAMF0,
+			StreamID:  1,
+			Data:      testPayload(1, 300),
+		}
+
+		if err := cs.writeChunk(chunk, 128); err != nil {
+			t.Fatalf("writeChunk() = %v", err)
+		}
+
+		// 3-byte basic header, 11-byte message header, 4-byte extended timestamp,
+		// and 3-byte basic header and 4-byte extended timestamp of 2 continuation chunks
+		if want := 3 + 11 + 4 + 2*(3+4) + 300; bc.buf.Len() != want {
+			t.Errorf("timestamp %#x: written %d bytes, want %d", timestamp, bc.buf.Len(), want)
+		}
+
+		got := readMessages(t, cs, 1)[0]
+
+		if got.Timestamp != timestamp || !bytes.Equal(got.Data, chunk.Data) {
+			t.Errorf("timestamp %#x: read timestamp %#x, %d bytes", timestamp, got.Timestamp, len(got.Data))
+		}
+	}
+}
+
+func TestBasicHeader(t *testing.T) {
+	tests := []struct {
+		csid   uint32
+		header []byte
+	}{
+		{2, []byte{0xC2}},
+		{63, []byte{0xFF}},
+		{64, []byte{0xC0, 0x00}},
+		{319, []byte{0xC0, 0xFF}},
+		{320, []byte{0xC1, 0x00, 0x01}},
+		{400, []byte{0xC1, 0x50, 0x01}},
+		{65599, []byte{0xC1, 0xFF, 0xFF}},
+	}
+
+	for _, test := range tests {
+		header := createBasicHeader(3, test.csid)
+		if !bytes.Equal(header, test.header) {
+			t.Errorf("createBasicHeader(3, %d) = % x, want % x", test.csid, header, test.header)
+		}
+
+		cs, bc := newTestChunkStream(t, &Config{}, 128)
+		bc.buf.Write(header)
+
+		format, csid, err := cs.readBasicHeader()
+		if err != nil || format != 3 || csid != test.csid {
+			t.Errorf("readBasicHeader(% x) = %d, %d, %v, want 3, %d", header, format, csid, err, test.csid)
+		}
+	}
+}
+
+// writeInterleaved writes chunks of non-empty messages alternately, one chunk of each message at a time.
+func writeInterleaved(t *testing.T, cs *ChunkStream, bc *bufferConn, chunkSize uint32, messages ...*Chunk) {
+	offsets := make([]uint32, len(messages))
+
+	for written := true; written; {
+		written = false
+
+		for i, chunk := range messages {
+			start := offsets[i]
+			if start >= chunk.Length {
+				continue
+			}
+
+			header := createBasicHeader(3, chunk.CSID)
+			if start == 0 {
+				var err error
+				header, err = cs.createChunkHeader(chunk)
+				if err != nil {
+					t.Fatalf("createChunkHeader() = %v", err)
+				}
+			}
+
+			end := start + chunkSize
+			if end > chunk.Length {
+				end = chunk.Length
+			}
+
+			bc.buf.Write(header)
+			bc.buf.Write(chunk.Data[start:end])
+			offsets[i] = end
+			written = true
+		}
+	}
+}
+
+func TestInterleavedMessages(t *testing.T) {
+	cs, bc := newTestChunkStream(t, &Config{}, 16)
+
+	// Audio on CSID with 2-byte basic header, video on CSID with 3-byte basic header
+	audio := &Chunk{CSID: 100, Timestamp: 20, Length: 40, TypeID: typeIDAudioMsg, StreamID: 1, Data: testPayload(0x10, 40)}
+	video := &Chunk{CSID: 400, Timestamp: 40, Length: 70, TypeID: typeIDVideoMsg, StreamID: 1, Data: testPayload(0x80, 70)}
+	nextAudio := &Chunk{CSID: 100, Timestamp: 43, Length: 40, TypeID: typeIDAudioMsg, StreamID: 1, Data: testPayload(0x40, 40)}
+
+	writeInterleaved(t, cs, bc, 16, audio, video)
+	writeInterleaved(t, cs, bc, 16, nextAudio)
+
+	// First chunk of audio and video
+	for i := 0; i < 2; i++ {
+		if err := cs.readChunk(); err != nil {
+			t.Fatalf("readChunk() = %v", err)
+		}
+	}
+
+	if cs.partialBytes != 32 {
+		t.Errorf("partialBytes = %d, want 32", cs.partialBytes)
+	}
+
+	// Payload is not allocated by the declared message length
+	if c := cap(cs.curRead[400].partial); c >= int(video.Length) {
+		t.Errorf("cap(partial) = %d after first chunk of %d bytes message", c, video.Length)
+	}
+
+	messages := readMessages(t, cs, 3)
+
+	for i, want := range []*Chunk{audio, video, nextAudio} {
+		got := messages[i]
+		if got.CSID != want.CSID || got.Timestamp != want.Timestamp || got.TypeID != want.TypeID ||
+			!bytes.Equal(got.Data, want.Data) {
+			t.Errorf("message %d = %+v, want %+v", i, got, want)
+		}
+	}
+
+	if cs.partialBytes != 0 || bc.buf.Len() != 0 {
+		t.Errorf("partialBytes = %d, %d bytes left unread, want 0", cs.partialBytes, bc.buf.Len())
+	}
+}
+
+func TestPartialMessagesBufferLimit(t *testing.T) {
+	cs, bc := newTestChunkStream(t, &Config{MaxBufferedBytes: 100}, 16)
+
+	video := &Chunk{CSID: 400, Timestamp: 40, Length: 80, TypeID: typeIDVideoMsg, StreamID: 1, Data: testPayload(0x80, 80)}
+	audio := &Chunk{CSID: 100, Timestamp: 20, Length: 90, TypeID: typeIDAudioMsg, StreamID: 1, Data: testPayload(0x10, 90)}
+
+	header, err := cs.createChunkHeader(video)
+	if err != nil {
+		t.Fatalf("createChunkHeader() = %v", err)
+	}
+
+	bc.buf.Write(header)
+	bc.buf.Write(video.Data[:16])
+
+	if err := cs.readChunk(); err != nil {
+		t.Fatalf("readChunk() = %v", err)
+	}
+
+	// Partial video message is aborted, releasing its buffered bytes
+	if err := cs.writeChunk(NewPCMChunk(typeIDAbortMsg, []byte{0, 0, 0x01, 0x90}), 16); err != nil {
+		t.Fatalf("writeChunk() = %v", err)
+	}
+
+	if err := cs.readChunk(); err != nil {
+		t.Fatalf("readChunk() = %v", err)
+	}
+
+	if cs.partialBytes != 0 || cs.curRead[400].partial != nil {
+		t.Errorf("partialBytes = %d after abort, want 0", cs.partialBytes)
+	}
+
+	// 16 bytes of partial video and 90 bytes of audio exceed the limit of 100 bytes
+	header, _ = cs.createChunkHeader(video)
+	bc.buf.Write(header)
+	bc.buf.Write(video.Data[:16])
+
+	if err := cs.readChunk(); err != nil {
+		t.Fatalf("readChunk() = %v", err)
+	}
+
+	header, _ = cs.createChunkHeader(audio)
+	bc.buf.Write(header)
+	bc.buf.Write(audio.Data[:16])
+
+	if err := cs.readChunk(); err != ErrBufferExceeded {
+		t.Errorf("readChunk() = %v, want %v", err, ErrBufferExceeded)
+	}
+}
+
+func TestChunkStreamsLimit(t *testing.T) {
+	cs, bc := newTestChunkStream(t, &Config{MaxChunkStreams: 2}, 128)
+
+	message := func(csid uint32) *Chunk {
+		return &Chunk{CSID: csid, Length: 10, TypeID: typeISSharedObjectMsgAMF0, StreamID: 1, Data: testPayload(0, 10)}
+	}
+
+	for _, csid := range []uint32{3, 4, 3} {
+		if err := cs.writeChunk(message(csid), 128); err != nil {
+			t.Fatalf("writeChunk() = %v", err)
+		}
+
+		if err := cs.readChunk(); err != nil {
+			t.Fatalf("CSID %d: readChunk() = %v", csid, err)
+		}
+	}
+
+	// Chunk streams are counted even if their messages have completed
+	if err := cs.writeChunk(message(5), 128); err != nil {
+		t.Fatalf("writeChunk() = %v", err)
+	}
+
+	if err := cs.readChunk(); err != ErrTooManyChunkStreams {
+		t.Errorf("readChunk() = %v, want %v", err, ErrTooManyChunkStreams)
+	}
+
+	if bc.buf.Len() == 0 {
+		t.Error("Payload of rejected message read")
+	}
+}
+
+func TestChunkHeaderUnexpectedEOF(t *testing.T) {
+	// Type 0 header on CSID 3 with extended timestamp
+	header := []byte{0x03, 0xff, 0xff, 0xff, 0x00, 0x00, 0x0a, 0x13, 0x01, 0x00, 0x00, 0x00, 0x01, 0x00, 0x00, 0x00}
+
+	tests := []struct {
+		name  string
+		input []byte
+	}{
+		{"1-byte of 2-byte basic header", []byte{0x00}},
+		{"1-byte of 3-byte basic header", []byte{0x01}},
+		{"2-byte of 3-byte basic header", []byte{0x01, 0x50}},
+		{"basic header only", header[:1]},
+		{"1-byte message header", header[:2]},
+		{"short message header", header[:8]},
+		{"message header without extended timestamp", header[:12]},
+		{"short extended timestamp", header[:14]},
+		{"message header without payload", header},
+		{"short payload", append(append([]byte{}, header...), 0x01, 0x02)},
+	}
+
+	for _, test := range tests {
+		cs, bc := newTestChunkStream(t, &Config{}, 128)
+		bc.buf.Write(test.input)
+
+		if err := cs.readChunk(); err != io.ErrUnexpectedEOF {
+			t.Errorf("%s: readChunk() = %v, want %v", test.name, err, io.ErrUnexpectedEOF)
+		}
+	}
+
+	// Connection closed between chunks
+	cs, _ := newTestChunkStream(t, &Config{}, 128)
+
+	if err := cs.readChunk(); err != io.EOF {
+		t.Errorf("readChunk() of empty input = %v, want %v", err, io.EOF)
+	}
+}
@@ -0,0 +1,126 @@
+package rtmp
+
+import (
+	"encoding/json"
+	"errors"
+	"os"
+	"time"
+)
+
+// Name of the virtual host used when client's tcUrl matches none of the configured virtual hosts
+const DefaultVHost = "__defaultVhost__"
+
+type Config struct {
+	VHosts              []*VHostConfig `json:"vhosts"`
+	Allow               []string       `json:"allow"`               // CIDRs allowed to connect, all IPs are allowed if empty
+	Deny                []string       `json:"deny"`                // CIDRs denied to connect
+	MaxConnections      int            `json:"maxConnections"`      // Maximum number of concurrent connections, unlimited if 0
+	MaxConnectionsPerIP int            `json:"maxConnectionsPerIP"` // Maximum number of concurrent connections per source IP, unlimited if 0
+
+	// Timeouts use defaults if not set and are disabled if negative
+	HandshakeTimeout Duration `json:"handshakeTimeout"` // Timeout of the whole handshake, 10 seconds by default
+	IdleTimeout      Duration `json:"idleTimeout"`      // Timeout of receiving no media or commands, 60 seconds by default
+	PublishTimeout   Duration `json:"publishTimeout"`   // Timeout of publisher sending no media, 30 seconds by default
+	WriteTimeout     Duration `json:"writeTimeout"`     // Timeout of each write, 30 seconds by default
+
+	// Limits use defaults if not set, clients exceeding limits are disconnected
+	MaxChunkSize     int            `json:"maxChunkSize"`     // Maximum chunk size set by client, 64 KB by default
+	MaxChunkStreams  int            `json:"maxChunkStreams"`  // Maximum number of chunk streams used over a connection's lifetime, 64 by default
+	MaxBufferedBytes int            `json:"maxBufferedBytes"` // Maximum bytes of partial messages buffered per connection, 64 MB by default
+	MaxMessageSizes  map[string]int `json:"maxMessageSizes"`  // Map<Message Type>Maximum message length
+}
+
+type VHostConfig struct {
+	Name           string       `json:"name"`           // Host name matched against the host of tcUrl or ?vhost= query
+	Aliases        []string     `json:"aliases"`        // Other host names resolved to this virtual host
+	Apps           []*AppConfig `json:"apps"`           // Permitted applications, all applications are permitted if empty
+	PublishKeyFile string       `json:"publishKeyFile"` // Path of publish key file, publishers are not authenticated if empty
+	Hooks          *HooksConfig `json:"hooks"`          // HTTP callbacks of applications without their own hooks
+	TLSCert        string       `json:"tlsCert"`        // Path of certificate file selected by SNI on RTMPS
+	TLSKey         string       `json:"tlsKey"`         // Path of key file of TLSCert
+}
+
+type AppConfig struct {
+	Name         string       `json:"name"`
+	SignSecret   string       `json:"signSecret"`   // Secret of signed URLs, signed URLs are not required if empty
+	SignSkew     Duration     `json:"signSkew"`     // Clock skew tolerated when checking expiry of signed URLs
+	SignBindIP   bool         `json:"signBindIP"`   // Whether signed URLs are bound to client's IP
+	Hooks        *HooksConfig `json:"hooks"`        // HTTP callbacks, override hooks of virtual host if set
+	PublishAllow []string     `json:"publishAllow"` // CIDRs allowed to publish, all IPs are allowed if empty
+	PublishDeny  []string     `json:"publishDeny"`  // CIDRs denied to publish
+	PlayAllow    []string     `json:"playAllow"`    // CIDRs allowed to play, all IPs are allowed if empty
+	PlayDeny     []string     `json:"playDeny"`     // CIDRs denied to play
+	LegacyHEVC   bool         `json:"legacyHEVC"`   // Whether to accept HEVC in FLV with non-standard CodecID 12
+}
+
+// HooksConfig lists the URLs POSTed on each action, in order.
+type HooksConfig struct {
+	OnConnect    []string `json:"on_connect"`
+	OnPublish    []string `json:"on_publish"`
+	OnUnpublish  []string `json:"on_unpublish"`
+	OnPlay       []string `json:"on_play"`
+	OnStop       []string `json:"on_stop"`
+	Timeout      Duration `json:"timeout"`      // Timeout of each request, 5 seconds if not set
+	AdmitTimeout Duration `json:"admitTimeout"` // Timeout of all admission hooks and retries of an action, 10 seconds if not set
+	Retries      int      `json:"retries"`      // Number of retries on network errors
+	FailOpen     bool     `json:"failOpen"`     // Whether to admit actions if hooks are unreachable
+}
+
+// Duration is a time.Duration decoded from either a JSON string
+// parsed by time.ParseDuration (e.g. "30s") or a JSON number of seconds.
+type Duration struct {
+	time.Duration
+}
+
+func (d *Duration) UnmarshalJSON(b []byte) error {
+	var v interface{}
+	err := json.Unmarshal(b, &v)
+	if err != nil {
+		return err
+	}
+
+	switch value := v.(type) {
+	case float64:
+		d.Duration = time.Duration(value * float64(time.Second))
+	case string:
+		d.Duration, err = time.ParseDuration(value)
+		if err != nil {
+			return err
+		}
+	default:
+		return errors.New("Invalid duration")
+	}
+
+	return nil
+}
+
+// LoadConfig reads server configuration from the JSON file at path.
+func LoadConfig(path string) (*Config, error) {
+	buf, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	config := &Config{}
+	err = json.Unmarshal(buf, config)
+	if err != nil {
+		return nil, err
+	}
+
+	return config, nil
+}
+
+// DefaultVHostConfig returns the configuration of the default virtual host,
+// the configuration is created if it does not exist.
+func (config *Config) DefaultVHostConfig() *VHostConfig {
+	for _, vhost := range config.VHosts {
+		if vhost.Name == DefaultVHost {
+			return vhost
+		}
+	}
+
+	vhost := &VHostConfig{Name: DefaultVHost}
+	config.VHosts = append(config.VHosts, vhost)
+
+	return vhost
+}
@@ -1,11 +1,19 @@
 package rtmp
 
 import (
-	"encoding/binary"
 	"errors"
+	"io"
 	"net"
+	"net/url"
+	"strings"
+	"sync/atomic"
+	"time"
 
 	bin "github.com/frankchang0125/go-live-stream/binary"
+	"github.com/frankchang0125/go-live-stream/codec/aac"
+	"github.com/frankchang0125/go-live-stream/codec/h264"
+	"github.com/frankchang0125/go-live-stream/codec/mp3"
+	"github.com/frankchang0125/go-live-stream/codec/opus"
 	"github.com/frankchang0125/go-live-stream/rtmp/amf"
 	"github.com/frankchang0125/go-live-stream/rtmp/flv"
 	log "github.com/sirupsen/logrus"
@@ -49,6 +57,8 @@ const (
 
 const packetBufLen = 1024 // Hold 1024 packets at most
 
+var lastConnID uint64 // ID of the latest accepted connection
+
 type ConnInfo struct {
 	app         string
 	flashVer    string
@@ -60,15 +70,18 @@ type ConnInfo struct {
 	videoFunc   float64
 	pageURL     string
 	amfEncoding float64
+	fourCcList  []string // Enhanced RTMP codecs supported by client
 }
 
 type PublishOrPlayInfo struct {
-	Name string
-	Type string
+	Name   string     // Stream name with query string stripped
+	Type   string
+	Params url.Values // Query string parameters split off the stream name
 }
 
 type Conn struct {
 	net.Conn
+	id               uint64
 	chunkSize        uint32 // Size of chunk sent from server (Server -> chunk -> Client)
 	clientChunkSize  uint32 // Size of chunk received from client (Server <- chunk <- Client)
 	windowAckSize    uint32 // Window acknowledgement size of server
@@ -82,19 +95,32 @@ type Conn struct {
 						// Stream ID is increased only and the largest Stream ID
 						// is placed at the end of slice
 	closed         bool
+	handshaked     bool  // Whether handshake has completed, read timeouts apply after handshake
+	lastWrite      int64 // Time of the latest successful write in Unix nanoseconds
+	lastMedia      int64 // Time of the latest received media message in Unix nanoseconds
+
+	legacyHEVCWarned bool // Whether dropping of legacy HEVC has been logged
+	keyFrames        *keyFrameAnalyser
+	tracks           trackDetector
+	lastAudioCodec   string // Codec of the latest audio message logged
+	playStarted      bool   // Whether any media has been written to player
+	playBase         uint32 // Timestamp of the first media written to player
+	playReady        bool   // Whether player has reached a packet to start from, media before it is dropped
 	isPublisher    bool
-	newStreamer    chan *Conn
-	newViewer      chan *Conn
+	server         *Server
+	vhost          *VHost     // Virtual host resolved from tcUrl on connect
+	appConfig      *AppConfig // Configuration of app connected
 	channelCreated chan bool    // Get notfiy when stream channel has been created by server successfully
 	channel        *Channel     // Streaming channel
 	broadcast      chan *Packet // Channel to deliver streaming video and audio packets
 	player         chan *Chunk  // Channel to receive decoded streaming video and audio chunks
-	quit           chan bool    // Quit notify channel
+	quit           chan bool    // Quit notify channel, closed when connection is closed
 }
 
-func NewConn(c net.Conn, newStreamer chan *Conn, newViewer chan *Conn) *Conn {
+func NewConn(c net.Conn, server *Server) *Conn {
 	return &Conn{
 		Conn:             c,
+		id:               atomic.AddUint64(&lastConnID, 1),
 		chunkSize:        128,
 		clientChunkSize:  128,
 		windowAckSize:    2500000,
@@ -104,8 +130,7 @@ func NewConn(c net.Conn, newStreamer chan *Conn, newViewer chan *Conn) *Conn {
 		ConnInfo:         ConnInfo{amfEncoding: amf.AMF0},
 		StreamIDs:        []float64{0}, // Default message stream for controls and commands with Message Stream ID: 0
 		closed:           false,
-		newStreamer:      newStreamer,
-		newViewer:        newViewer,
+		server:           server,
 		channelCreated:   make(chan bool),
 		broadcast:        make(chan *Packet, packetBufLen),
 		player:           make(chan *Chunk),
@@ -119,15 +144,16 @@ func (c *Conn) Serve() {
 
 	defer func() {
 		// Quit underlying go routines
-		select {
-		case c.quit <- true:
-			break
-		}
+		close(c.quit)
 	}()
 
 	for {
 		err := cs.readChunk()
 		if err != nil {
+			if err != io.EOF {
+				log.WithField("err", err).Info("Closing connection.")
+			}
+
 			c.closed = true
 		}
 
@@ -139,14 +165,12 @@ func (c *Conn) Serve() {
 }
 
 func (c *Conn) handleUsrCtrlMsg(cs *ChunkStream, chunk *Chunk) error {
-	buf, err := cs.readBytes(chunk.Length)
+	eventType, err := bin.NewReader(chunk.Data).U16BE()
 	if err != nil {
 		log.WithField("err", err).Error("Error while reading user control message.")
 		return err
 	}
 
-	eventType := bin.U16BE(buf[:2])
-
 	// TODO: Handle message
 	//eventDataLength := cs.curRead.Length - 2
 	//eventData := buf[2:]
@@ -165,11 +189,7 @@ func (c *Conn) handleUsrCtrlMsg(cs *ChunkStream, chunk *Chunk) error {
 }
 
 func (c *Conn) handleCmdMsg(cs *ChunkStream, chunk *Chunk) error {
-	buf, err := cs.readAMFBody(chunk.Length, c.clientChunkSize)
-	if err != nil {
-		log.WithField("err", err).Error("Error while reading command message.")
-		return err
-	}
+	buf := chunk.Data
 
 	amfDecoded, err := amf.DecodeAMF(buf, c.amfEncoding)
 	if err != nil {
@@ -189,6 +209,33 @@ func (c *Conn) handleCmdMsg(cs *ChunkStream, chunk *Chunk) error {
 				return err
 			}
 
+			if !c.vhost.isAppPermitted(c.app) {
+				log.WithFields(log.Fields{
+					"vhost": c.vhost.Name(),
+					"app":   c.app,
+				}).Warning("Connecting to unknown app, reject connection.")
+				err = c.connectErrorResp(cs, chunk, "NetConnection.Connect.InvalidApp",
+					"The application name specified during connect is invalid.")
+				if err != nil {
+					return err
+				}
+
+				return errors.New("Invalid app")
+			}
+
+			c.appConfig = c.vhost.appConfig(c.app)
+
+			err = c.vhost.hooksOf(c.app).Admit(c.hookEvent(HookOnConnect))
+			if err != nil {
+				respErr := c.connectErrorResp(cs, chunk, "NetConnection.Connect.Rejected",
+					"The connection attempt was rejected.")
+				if respErr != nil {
+					return respErr
+				}
+
+				return err
+			}
+
 			return c.connectResp(cs, chunk)
 		case cmdCall:
 		// TODO:
@@ -203,6 +250,10 @@ func (c *Conn) handleCmdMsg(cs *ChunkStream, chunk *Chunk) error {
 		case cmdPlay:
 			err = c.play(amfDecoded[1:])
 			if err != nil {
+				if statusErr, ok := err.(*statusError); ok {
+					c.statusErrorResp(cs, chunk, statusErr)
+				}
+
 				return err
 			}
 
@@ -228,6 +279,10 @@ func (c *Conn) handleCmdMsg(cs *ChunkStream, chunk *Chunk) error {
 		case cmdPublish:
 			err = c.publish(amfDecoded[1:])
 			if err != nil {
+				if statusErr, ok := err.(*statusError); ok {
+					c.statusErrorResp(cs, chunk, statusErr)
+				}
+
 				return err
 			}
 
@@ -263,19 +318,39 @@ func (c *Conn) handleCmdMsg(cs *ChunkStream, chunk *Chunk) error {
 }
 
 func (c *Conn) handleDataMsg(cs *ChunkStream, chunk *Chunk) error {
-	buf, err := cs.readAMFBody(chunk.Length, c.clientChunkSize)
+	buf := chunk.Data
+
+	amfDecoded, err := amf.DecodeAMF(buf, c.amfEncoding)
 	if err != nil {
 		log.WithField("err", err).Error("Error while reading command message.")
 		return err
 	}
 
-	_, err = amf.DecodeAMF(buf, c.amfEncoding)
-	if err != nil {
-		log.WithField("err", err).Error("Error while reading command message.")
-		return err
+	// Publishers send metadata either as "@setDataFrame", "onMetaData", <metadata>
+	// or "onMetaData", <metadata>
+	if len(amfDecoded) > 0 && amfDecoded[0] == "@setDataFrame" {
+		amfDecoded = amfDecoded[1:]
+	}
+
+	if len(amfDecoded) < 2 || amfDecoded[0] != "onMetaData" {
+		// TODO: Handle other data messages
+		return nil
 	}
 
-	// TODO: Handle the decoded data message
+	if !c.isPublisher || c.channel == nil {
+		log.Warning("Received metadata before publishing, ignored.")
+		return nil
+	}
+
+	metaData, ok := amfDecoded[1].(amf.Object)
+	if !ok {
+		log.Warning("Invalid metadata, ignored.")
+		return nil
+	}
+
+	if c.tracks.onMetaData(metaData) {
+		c.updateTracks()
+	}
 
 	return nil
 }
@@ -285,47 +360,290 @@ func (c *Conn) handleSharedObjectMsg(cs *ChunkStream) error {
 }
 
 func (c *Conn) handleAudioMsg(cs *ChunkStream, chunk *Chunk) error {
-	buf, err := cs.readAMFBody(chunk.Length, c.clientChunkSize)
+	buf := chunk.Data
+
+	if !c.isPublisher || c.channel == nil {
+		log.Warning("Received audio message before publishing, ignored.")
+		return nil
+	}
+
+	atomic.StoreInt64(&c.lastMedia, time.Now().UnixNano())
+
+	audio, err := flv.DecodeAudio(buf)
 	if err != nil {
-		log.WithField("err", err).Error("Error while reading audio message.")
-		return err
+		log.WithFields(log.Fields{
+			"name": c.info.Name,
+			"err":  err,
+		}).Warning("Malformed audio message, ignored.")
+		return nil
+	}
+
+	if audio.IsOpus() && audio.AudioPacketType == flv.AudioPacketTypeCodedFrames {
+		// Validate TOC of Opus packet, whose frame count and duration are parsed without decoding
+		duration, err := opus.PacketDuration(audio.Data)
+		if err != nil {
+			log.WithFields(log.Fields{
+				"name": c.info.Name,
+				"err":  err,
+			}).Warning("Malformed Opus packet, ignored.")
+			return nil
+		}
+
+		log.WithFields(log.Fields{
+			"name":      c.info.Name,
+			"timestamp": chunk.Timestamp,
+			"duration":  duration,
+		}).Debug("Opus packet received.")
+	}
+
+	if audio.SoundFormat == flv.SoundFormatAAC && audio.IsSequenceHeader() {
+		c.updateAudioInfo(audio.Data)
+	} else if audio.IsOpus() && audio.IsSequenceHeader() {
+		c.updateOpusHead(audio.Data)
+	} else if audio.CodecName() != c.lastAudioCodec {
+		c.logAudioCodec(audio)
 	}
 
-	packet := NewPacket(typeAudio, chunk.Timestamp, chunk.StreamID, buf)
+	timestamp := c.channel.timestamps.normalize(c, typeAudio, chunk.Timestamp)
+
+	if c.tracks.onPacket(typeAudio, timestamp) {
+		c.updateTracks()
+	}
+
+	packet := NewPacket(typeAudio, timestamp, chunk.StreamID, buf)
+	packet.sequenceHeader = audio.IsSequenceHeader()
 	c.broadcast <- packet
 
 	return nil
 }
 
 func (c *Conn) handleVideoMsg(cs *ChunkStream, chunk *Chunk) error {
-	buf, err := cs.readAMFBody(chunk.Length, c.clientChunkSize)
+	buf := chunk.Data
+
+	if !c.isPublisher || c.channel == nil {
+		log.Warning("Received video message before publishing, ignored.")
+		return nil
+	}
+
+	atomic.StoreInt64(&c.lastMedia, time.Now().UnixNano())
+
+	video, err := flv.DecodeVideo(buf)
 	if err != nil {
-		log.WithField("err", err).Error("Error while reading video message.")
-		return err
+		log.WithFields(log.Fields{
+			"name": c.info.Name,
+			"err":  err,
+		}).Warning("Malformed video message, ignored.")
+		return nil
 	}
 
-	packet := NewPacket(typeVideo, chunk.Timestamp, chunk.StreamID, buf)
+	// Legacy HEVC (CodecID 12) is accepted only if enabled on app
+	if !video.IsExHeader && video.IsHEVC() && !c.appConfig.LegacyHEVC {
+		if !c.legacyHEVCWarned {
+			log.WithFields(log.Fields{
+				"app":  c.app,
+				"name": c.info.Name,
+			}).Warning("Legacy HEVC (CodecID 12) is not enabled on app, dropping video.")
+			c.legacyHEVCWarned = true
+		}
+
+		return nil
+	}
+
+	if video.IsAVC() && video.IsSequenceHeader() {
+		c.updateVideoInfo(video.Data)
+	}
+
+	timestamp := c.channel.timestamps.normalize(c, typeVideo, chunk.Timestamp)
+
+	if c.tracks.onPacket(typeVideo, timestamp) {
+		c.updateTracks()
+	}
+
+	packet := NewPacket(typeVideo, timestamp, chunk.StreamID, buf)
+	packet.keyFrame = c.analyseKeyFrame(video)
+	packet.sequenceHeader = video.IsSequenceHeader()
 	c.broadcast <- packet
 
 	return nil
 }
 
+// updateTracks saves tracks detected to channel.
+func (c *Conn) updateTracks() {
+	tracks := c.tracks.tracks
+
+	c.channel.lock.Lock()
+	c.channel.tracks = tracks
+	c.channel.lock.Unlock()
+
+	log.WithFields(log.Fields{
+		"name":  c.info.Name,
+		"audio": tracks.Audio,
+		"video": tracks.Video,
+	}).Info("Stream tracks detected.")
+}
+
+// analyseKeyFrame reports whether video is a random access point,
+// encoders whose FrameType disagrees with coded frames are logged on first disagreement.
+func (c *Conn) analyseKeyFrame(video *flv.VideoBody) bool {
+	if c.keyFrames == nil {
+		c.keyFrames = newKeyFrameAnalyser()
+	}
+
+	falseKeyFrames := c.keyFrames.falseKeyFrames
+	missedKeyFrames := c.keyFrames.missedKeyFrames
+
+	keyFrame, analysed, err := c.keyFrames.analyse(video)
+	if err != nil {
+		log.WithFields(log.Fields{
+			"name": c.info.Name,
+			"err":  err,
+		}).Debug("Cannot analyse video coded frames, trust FrameType.")
+	}
+
+	if !analysed {
+		return keyFrame
+	}
+
+	if falseKeyFrames == 0 && c.keyFrames.falseKeyFrames == 1 {
+		log.WithFields(log.Fields{
+			"app":  c.app,
+			"name": c.info.Name,
+		}).Warning("Encoder flags frames without random access point as key frames.")
+	}
+
+	if missedKeyFrames == 0 && c.keyFrames.missedKeyFrames == 1 {
+		log.WithFields(log.Fields{
+			"app":  c.app,
+			"name": c.info.Name,
+		}).Warning("Encoder flags random access points as inter frames.")
+	}
+
+	return keyFrame
+}
+
+// updateVideoInfo parses AVC sequence header and saves stream information to channel.
+func (c *Conn) updateVideoInfo(sequenceHeader []byte) {
+	info, err := h264.ParseInfo(sequenceHeader)
+	if err != nil {
+		log.WithFields(log.Fields{
+			"name": c.info.Name,
+			"err":  err,
+		}).Warning("Cannot parse AVC sequence header.")
+		return
+	}
+
+	c.channel.lock.Lock()
+	c.channel.videoInfo = info
+	c.channel.lock.Unlock()
+
+	log.WithFields(log.Fields{
+		"name":      c.info.Name,
+		"profile":   info.Profile,
+		"level":     info.Level,
+		"width":     info.Width,
+		"height":    info.Height,
+		"frameRate": info.FrameRate,
+	}).Info("AVC sequence header received.")
+}
+
+// logAudioCodec logs the codec of published audio on first audio message and on codec changes,
+// sample rate of MP3 is parsed from frame header since SoundRate cannot express 48 kHz.
+func (c *Conn) logAudioCodec(audio *flv.AudioBody) {
+	c.lastAudioCodec = audio.CodecName()
+	sampleRate := audio.SampleRate()
+	channels := audio.Channels()
+
+	if c.lastAudioCodec == "mp3" {
+		header, err := mp3.ParseFrameHeader(audio.Data)
+		if err != nil {
+			log.WithFields(log.Fields{
+				"name": c.info.Name,
+				"err":  err,
+			}).Warning("Cannot parse MP3 frame header.")
+		} else {
+			sampleRate = header.SampleRate
+			channels = header.Channels()
+		}
+	}
+
+	log.WithFields(log.Fields{
+		"name":       c.info.Name,
+		"codec":      c.lastAudioCodec,
+		"sampleRate": sampleRate,
+		"channels":   channels,
+	}).Info("Audio codec detected.")
+}
+
+// updateAudioInfo parses AAC sequence header and saves audio configuration to channel.
+func (c *Conn) updateAudioInfo(sequenceHeader []byte) {
+	config, err := aac.ParseAudioSpecificConfig(sequenceHeader)
+	if err != nil {
+		log.WithFields(log.Fields{
+			"name": c.info.Name,
+			"err":  err,
+		}).Warning("Cannot parse AAC sequence header.")
+		return
+	}
+
+	c.channel.lock.Lock()
+	c.channel.audioInfo = config
+	c.channel.lock.Unlock()
+
+	log.WithFields(log.Fields{
+		"name":       c.info.Name,
+		"objectType": config.ObjectType,
+		"sampleRate": config.OutputSampleRate(),
+		"channels":   config.Channels(),
+		"sbr":        config.SBR,
+		"ps":         config.PS,
+	}).Info("AAC sequence header received.")
+}
+
+// updateOpusHead parses Opus sequence header and saves identification header to channel.
+func (c *Conn) updateOpusHead(sequenceHeader []byte) {
+	head, err := opus.ParseHead(sequenceHeader)
+	if err != nil {
+		log.WithFields(log.Fields{
+			"name": c.info.Name,
+			"err":  err,
+		}).Warning("Cannot parse Opus sequence header.")
+		return
+	}
+
+	c.channel.lock.Lock()
+	c.channel.opusHead = head
+	c.channel.lock.Unlock()
+
+	c.lastAudioCodec = flv.FourCCOpus
+
+	log.WithFields(log.Fields{
+		"name":            c.info.Name,
+		"channels":        head.Channels,
+		"preSkip":         head.PreSkip,
+		"inputSampleRate": head.InputSampleRate,
+	}).Info("Opus sequence header received.")
+}
+
 func (c *Conn) handleAggregateMsg() error {
 	return nil
 }
 
 // Protocol Control Messages
 
-func (c *Conn) setChunkSize() error {
-	var chunkSize uint32
-	err := binary.Read(c, binary.BigEndian, &chunkSize)
+func (c *Conn) setChunkSize(chunk *Chunk) error {
+	chunkSize, err := bin.NewReader(chunk.Data).U32BE()
 	if err != nil {
 		log.WithField("err", err).Error("Error while handling set chunk size command.")
 		return err
 	}
 
-	if chunkSize > 0xFFFFFF {
-		chunkSize = 0xFFFFFF
+	err = c.server.checkChunkSize(chunkSize)
+	if err != nil {
+		log.WithFields(log.Fields{
+			"chunkSize": chunkSize,
+			"err":       err,
+		}).Error("Error while handling set chunk size command.")
+		return err
 	}
 
 	c.clientChunkSize = chunkSize
@@ -340,11 +658,11 @@ func (c *Conn) respChunkSize(cs *ChunkStream) error {
 	return cs.writeChunk(chunk, c.chunkSize)
 }
 
-func (c *Conn) setWindowAckSize() error {
-	var windowAckSize uint32
-	err := binary.Read(c, binary.BigEndian, &windowAckSize)
+func (c *Conn) setWindowAckSize(chunk *Chunk) error {
+	windowAckSize, err := bin.NewReader(chunk.Data).U32BE()
 	if err != nil {
 		log.WithField("err", err).Error("Error while handling set window ack size command.")
+		return err
 	}
 
 	c.clientWindowSize = windowAckSize
@@ -452,9 +770,24 @@ func (c *Conn) connect(packets []interface{}) error {
 			if objEncoding, ok := obj["objectEncoding"]; ok {
 				c.amfEncoding = objEncoding.(float64)
 			}
+
+			if fourCcList, ok := obj["fourCcList"].([]interface{}); ok {
+				for _, fourCc := range fourCcList {
+					if s, ok := fourCc.(string); ok {
+						c.fourCcList = append(c.fourCcList, s)
+					}
+				}
+			}
 		}
 	}
 
+	c.vhost = c.server.resolveVHost(c.tcURL, c.app)
+
+	// Strip query (e.g. ?vhost=) from app name
+	if i := strings.Index(c.app, "?"); i >= 0 {
+		c.app = c.app[:i]
+	}
+
 	return nil
 }
 
@@ -482,6 +815,8 @@ func (c *Conn) connectResp(cs *ChunkStream, chunk *Chunk) error {
 	props := amf.Object{}
 	props["fmsVer"] = "FMS/3,0,1,123"
 	props["capabilities"] = 31
+	// Enhanced RTMP codecs supported by server
+	props["fourCcList"] = flv.SupportedFourCCs
 
 	info := amf.Object{}
 	info["code"] = "NetConnection.Connect.Success"
@@ -492,6 +827,23 @@ func (c *Conn) connectResp(cs *ChunkStream, chunk *Chunk) error {
 	return c.cmdResp(cs, chunk, cmdName, transactionID, props, info)
 }
 
+func (c *Conn) connectErrorResp(cs *ChunkStream, chunk *Chunk, code string, description string) error {
+	cmdName := "_error"
+	var transactionID float64 = 1
+
+	props := amf.Object{}
+	props["fmsVer"] = "FMS/3,0,1,123"
+	props["capabilities"] = 31
+
+	info := amf.Object{}
+	info["code"] = code
+	info["level"] = "error"
+	info["description"] = description
+	info["objectEncoding"] = c.amfEncoding
+
+	return c.cmdResp(cs, chunk, cmdName, transactionID, props, info)
+}
+
 func (c *Conn) createStream(packets []interface{}) error {
 	// Increase largest Message Stream ID as allocating a new stream for client
 	newStreamID := c.StreamIDs[len(c.StreamIDs)-1] + 1
@@ -542,11 +894,53 @@ func (c *Conn) play(packets []interface{}) error {
 		c.info = &PublishOrPlayInfo{}
 	}
 
-	c.info.Name = streamName
+	c.info.Name, c.info.Params = splitStreamName(streamName)
+
+	if c.vhost == nil {
+		return errors.New("Playing before connect")
+	}
+
+	ip := remoteIP(c.RemoteAddr())
+
+	if !c.vhost.playACLs[c.app].Permit(ip) {
+		log.WithFields(log.Fields{
+			"vhost": c.vhost.Name(),
+			"app":   c.app,
+			"name":  c.info.Name,
+			"ip":    ip,
+		}).Warning("Player denied by ACL.")
+		return &statusError{
+			code:        "NetStream.Play.Failed",
+			description: "The player is not allowed to play the stream.",
+		}
+	}
+
+	err := VerifySignedURL(c.appConfig, SignActionPlay, c.info.Name, c.info.Params, ip, time.Now())
+	if err != nil {
+		log.WithFields(log.Fields{
+			"vhost": c.vhost.Name(),
+			"app":   c.app,
+			"name":  c.info.Name,
+			"err":   err,
+		}).Warning("Player signed URL verification failed.")
+		return &statusError{
+			code:        "NetStream.Play.Failed",
+			description: "The player is not authorized to play the stream.",
+		}
+	}
+
+	err = c.vhost.hooksOf(c.app).Admit(c.hookEvent(HookOnPlay))
+	if err != nil {
+		return &statusError{
+			code:        "NetStream.Play.Failed",
+			description: "The player was rejected.",
+		}
+	}
+
 	c.isPublisher = false
 
-	// Notify server there's a new streamer
-	c.newViewer <- c
+	// Notify server there's a new viewer
+	c.server.newViewer <- c
 
 	// Wait for server to create channel
 	<-c.channelCreated
@@ -650,17 +1044,70 @@ func (c *Conn) publish(packets []interface{}) error {
 			c.transactionID = p.(float64)
 		} else if i == 2 {
 			// Publishing Name
-			c.info.Name = p.(string)
+			c.info.Name, c.info.Params = splitStreamName(p.(string))
 		} else if i == 3 {
 			// Publishing Type
 			c.info.Type = p.(string)
 		}
 	}
 
+	if c.vhost == nil {
+		return errors.New("Publishing before connect")
+	}
+
+	ip := remoteIP(c.RemoteAddr())
+
+	if !c.vhost.publishACLs[c.app].Permit(ip) {
+		log.WithFields(log.Fields{
+			"vhost": c.vhost.Name(),
+			"app":   c.app,
+			"name":  c.info.Name,
+			"ip":    ip,
+		}).Warning("Publisher denied by ACL.")
+		return publishStatusError(ErrUnauthorized)
+	}
+
+	err := VerifySignedURL(c.appConfig, SignActionPublish, c.info.Name, c.info.Params, ip, time.Now())
+	if err != nil {
+		log.WithFields(log.Fields{
+			"vhost": c.vhost.Name(),
+			"app":   c.app,
+			"name":  c.info.Name,
+			"err":   err,
+		}).Warning("Publisher signed URL verification failed.")
+		return publishStatusError(ErrUnauthorized)
+	}
+
+	if c.vhost.publishAuth != nil {
+		err = c.vhost.publishAuth.AuthenticatePublish(&PublishRequest{
+			VHost:      c.vhost.Name(),
+			App:        c.app,
+			Name:       c.info.Name,
+			Params:     c.info.Params,
+			TcURL:      c.tcURL,
+			RemoteAddr: c.RemoteAddr(),
+		})
+		if err != nil {
+			log.WithFields(log.Fields{
+				"vhost": c.vhost.Name(),
+				"app":   c.app,
+				"name":  c.info.Name,
+				"err":   err,
+			}).Warning("Publisher authentication failed.")
+			return publishStatusError(err)
+		}
+	}
+
+	err = c.vhost.hooksOf(c.app).Admit(c.hookEvent(HookOnPublish))
+	if err != nil {
+		return publishStatusError(ErrUnauthorized)
+	}
+
 	c.isPublisher = true
+	atomic.StoreInt64(&c.lastMedia, time.Now().UnixNano())
 
 	// Notify server there's a new streamer
-	c.newStreamer <- c
+	c.server.newStreamer <- c
 
 	// Wait for server to create channel
 	<-c.channelCreated
@@ -728,6 +1175,43 @@ func (c *Conn) cmdResp(cs *ChunkStream, chunk *Chunk, cmdName string, transactio
 	return cs.writeChunk(amfCmdChunk, c.chunkSize)
 }
 
+// hookEvent returns the hook event of action taken by the connection.
+func (c *Conn) hookEvent(action string) *HookEvent {
+	event := &HookEvent{
+		Action:   action,
+		ClientID: c.id,
+		IP:       remoteIP(c.RemoteAddr()),
+		App:      c.app,
+		TcURL:    c.tcURL,
+		PageURL:  c.pageURL,
+	}
+
+	if c.vhost != nil {
+		event.VHost = c.vhost.Name()
+	}
+
+	if c.info != nil {
+		event.Stream = c.info.Name
+		event.Param = c.info.Params.Encode()
+	}
+
+	return event
+}
+
+func (c *Conn) statusErrorResp(cs *ChunkStream, chunk *Chunk, statusErr *statusError) error {
+	cmdName := "onStatus"
+	var transactionID float64 // = 0
+	cmdObj := interface{}(nil)
+
+	info := amf.Object{}
+	info["code"] = statusErr.code
+	info["level"] = "error"
+	info["description"] = statusErr.description
+	info["objectEncoding"] = c.amfEncoding
+
+	return c.cmdResp(cs, chunk, cmdName, transactionID, cmdObj, info)
+}
+
 func (c *Conn) broadcastVideo() {
 	for {
 		if c.closed {
@@ -754,18 +1238,32 @@ func (c *Conn) broadcastVideo() {
 			if chunk != nil {
 				c.channel.lock.RLock()
 				viewers := c.channel.viewers
+				tracks := c.channel.tracks
 
 				for _, viewer := range viewers {
+					// Viewers joining the stream skip media until a packet to start from,
+					// sequence headers are still sent for decoding the media followed
+					if !viewer.playReady {
+						if packet.startsPlay(tracks) {
+							viewer.playReady = true
+						} else if !packet.sequenceHeader {
+							continue
+						}
+					}
+
 					select {
 					case viewer.player <- chunk:
 						continue
+					case <-viewer.quit:
+						// Viewer has been closed but not removed from channel yet
+						continue
 					}
 				}
 				c.channel.lock.RUnlock()
 			}
 		case <-c.quit:
 			log.WithField("streamName", c.info.Name).Info("broadcastVideo quit.")
-			break
+			return
 		}
 	}
 }
@@ -777,12 +1275,33 @@ func (c *Conn) playVideo(cs *ChunkStream) {
 			// Double check if the connection has not been closed yet
 			if c.closed {
 				log.WithField("streamName", c.info.Name).Info("playVideo quit.")
-				break
+				return
+			}
+
+			// Timestamps start from zero for each player
+			if !c.playStarted {
+				c.playStarted = true
+				c.playBase = chunk.Timestamp
+			}
+
+			rebased := *chunk
+			rebased.Timestamp = rebaseTimestamp(chunk.Timestamp, c.playBase)
+
+			err := cs.writeChunk(&rebased, c.chunkSize)
+			if err != nil {
+				log.WithFields(log.Fields{
+					"streamName": c.info.Name,
+					"err":        err,
+				}).Info("Fail to write to player, closing connection.")
+
+				// Unblock reading of the connection
+				c.closed = true
+				c.Conn.Close()
+				return
 			}
-			cs.writeChunk(chunk, c.chunkSize)
 		case <-c.quit:
 			log.WithField("streamName", c.info.Name).Info("playVideo quit.")
-			break
+			return
 		}
 	}
 }
@@ -790,7 +1309,12 @@ func (c *Conn) playVideo(cs *ChunkStream) {
 func (p *Packet) decode() (chunk *Chunk) {
 	switch p.packetType {
 	case typeAudio:
-		audio := flv.DecodeAudio(p.data)
+		audio, err := flv.DecodeAudio(p.data)
+		if err != nil {
+			log.WithField("err", err).Error("Fail to decode audio packet.")
+			return nil
+		}
+
 		data := append(audio.AudioTagHeader.Encode(), audio.Data...)
 		chunk = NewAudioChunk(p.timestamp, p.streamID, data)
 		log.WithFields(log.Fields{
@@ -798,7 +1322,12 @@ func (p *Packet) decode() (chunk *Chunk) {
 			"timestamp": p.timestamp,
 		}).Debug("Decoded audio packet.")
 	case typeVideo:
-		video := flv.DecodeVideo(p.data)
+		video, err := flv.DecodeVideo(p.data)
+		if err != nil {
+			log.WithField("err", err).Error("Fail to decode video packet.")
+			return nil
+		}
+
 		data := append(video.VideoTagHeader.Encode(), video.Data...)
 		chunk = NewVideoChunk(p.timestamp, p.streamID, data)
 		log.WithFields(log.Fields{
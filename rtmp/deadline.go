@@ -0,0 +1,115 @@
+package rtmp
+
+import (
+	"errors"
+	"net"
+	"sync/atomic"
+	"time"
+)
+
+// Default timeouts, used if not configured
+const (
+	defaultHandshakeTimeout = 10 * time.Second
+	defaultIdleTimeout      = 60 * time.Second
+	defaultPublishTimeout   = 30 * time.Second
+	defaultWriteTimeout     = 30 * time.Second
+)
+
+var (
+	ErrIdleTimeout    = errors.New("Idle timeout, no media or commands received")
+	ErrPublishTimeout = errors.New("Publisher media timeout, no media received")
+	ErrWriteTimeout   = errors.New("Write timeout")
+)
+
+// timeout returns configured timeout, or def if not configured.
+// Negative timeouts disable the timeout and are returned as 0.
+func timeout(configured Duration, def time.Duration) time.Duration {
+	switch {
+	case configured.Duration < 0:
+		return 0
+	case configured.Duration == 0:
+		return def
+	default:
+		return configured.Duration
+	}
+}
+
+func isTimeout(err error) bool {
+	netErr, ok := err.(net.Error)
+	return ok && netErr.Timeout()
+}
+
+// Read reads from the underlying connection with idle timeout after handshake.
+// Publishers are also disconnected if no media is received within publisher media timeout.
+// Players, which do not send anything while receiving media, are kept alive as long as
+// media is written to them.
+func (c *Conn) Read(b []byte) (int, error) {
+	if !c.handshaked {
+		return c.Conn.Read(b)
+	}
+
+	idleTimeout := timeout(c.server.config.IdleTimeout, defaultIdleTimeout)
+	publishTimeout := timeout(c.server.config.PublishTimeout, defaultPublishTimeout)
+
+	for {
+		now := time.Now()
+		var deadline time.Time
+		waitingMedia := false
+
+		if idleTimeout > 0 {
+			deadline = now.Add(idleTimeout)
+		}
+
+		if c.isPublisher && c.channel != nil && publishTimeout > 0 {
+			mediaDeadline := time.Unix(0, atomic.LoadInt64(&c.lastMedia)).Add(publishTimeout)
+			if !now.Before(mediaDeadline) {
+				return 0, ErrPublishTimeout
+			}
+
+			if deadline.IsZero() || mediaDeadline.Before(deadline) {
+				deadline = mediaDeadline
+				waitingMedia = true
+			}
+		}
+
+		c.Conn.SetReadDeadline(deadline)
+
+		n, err := c.Conn.Read(b)
+		if err == nil || !isTimeout(err) {
+			return n, err
+		}
+
+		if waitingMedia {
+			return n, ErrPublishTimeout
+		}
+
+		// Nothing has been read and media is still being written to player, keep waiting
+		lastWrite := time.Unix(0, atomic.LoadInt64(&c.lastWrite))
+		if n == 0 && !c.isPublisher && time.Since(lastWrite) < idleTimeout {
+			continue
+		}
+
+		return n, ErrIdleTimeout
+	}
+}
+
+// Write writes to the underlying connection with write timeout after handshake,
+// writes during handshake are bounded by the deadline of the whole handshake instead.
+func (c *Conn) Write(b []byte) (int, error) {
+	if writeTimeout := timeout(c.server.config.WriteTimeout, defaultWriteTimeout); writeTimeout > 0 && c.handshaked {
+		c.Conn.SetWriteDeadline(time.Now().Add(writeTimeout))
+	}
+
+	n, err := c.Conn.Write(b)
+	if err != nil {
+		if isTimeout(err) {
+			return n, ErrWriteTimeout
+		}
+
+		return n, err
+	}
+
+	atomic.StoreInt64(&c.lastWrite, time.Now().UnixNano())
+
+	return n, nil
+}
@@ -4,11 +4,18 @@ import (
     bin "github.com/frankchang0125/go-live-stream/binary"
 )
 
-func DecodeAudio(data []byte) *AudioBody {
-    soundFormat := (data[0] & 0xf0) >> 4
-    soundRate := (data[0] & 0xc) >> 2
-    soundSize := (data[0] & 0x2) >> 1
-    soundType := data[0] & 0x1
+func DecodeAudio(data []byte) (*AudioBody, error) {
+    r := bin.NewReader(data)
+
+    flags, err := r.U8()
+    if err != nil {
+        return nil, err
+    }
+
+    soundFormat := (flags & 0xf0) >> 4
+    soundRate := (flags & 0xc) >> 2
+    soundSize := (flags & 0x2) >> 1
+    soundType := flags & 0x1
 
     audioTagHeader := AudioTagHeader{
         SoundFormat: soundFormat,
@@ -16,62 +23,91 @@ func DecodeAudio(data []byte) *AudioBody {
         SoundSize: soundSize,
         SoundType: soundType,
     }
-   
-    var audioBody AudioBody
 
     // If SoundFormat == 10 (UI8)
-    if soundFormat == 10 {
-        audioTagHeader.AACPacketType = data[1]
-        audioData := data[2:]
-
-        audioBody = AudioBody{
-            //Tag: flvTag,
-            AudioTagHeader: audioTagHeader,
-            Data: audioData,
+    if soundFormat == SoundFormatAAC {
+        audioTagHeader.AACPacketType, err = r.U8()
+        if err != nil {
+            return nil, err
         }
-    } else {
-        audioData := data[1:]
+    } else if soundFormat == SoundFormatExHeader {
+        // Enhanced RTMP: lower 4 bits are AudioPacketType, followed by FourCC
+        audioTagHeader.AudioPacketType = flags & 0xf
 
-        audioBody = AudioBody{
-            //Tag: flvTag,
-            AudioTagHeader: audioTagHeader,
-            Data: audioData,
+        fourCC, err := r.Bytes(4)
+        if err != nil {
+            return nil, err
         }
+
+        audioTagHeader.FourCC = string(fourCC)
     }
 
-    return &audioBody
+    return &AudioBody{
+        AudioTagHeader: audioTagHeader,
+        Data: r.Rest(),
+    }, nil
 }
 
-func DecodeVideo(data []byte) *VideoBody {
-    frameType := (data[0] & 0xf0) >> 4
-    codecID := data[0] & 0xf
+func DecodeVideo(data []byte) (*VideoBody, error) {
+    r := bin.NewReader(data)
 
-    videoTagHeader := VideoTagHeader{
-        FrameType: frameType,
-        CodecID: codecID,
+    flags, err := r.U8()
+    if err != nil {
+        return nil, err
     }
 
-    var videoBody VideoBody
+    if flags & 0x80 != 0 {
+        return decodeExVideo(flags, r)
+    }
 
-    if codecID == 7 {
-        videoTagHeader.AVCPacketType = data[1]
-        videoTagHeader.CompositionTime = bin.I24BE(data[2:5])
-        videoData := data[5:]
+    videoTagHeader := VideoTagHeader{
+        FrameType: (flags & 0xf0) >> 4,
+        CodecID: flags & 0xf,
+    }
+
+    if videoTagHeader.hasAVCHeader() {
+        videoTagHeader.AVCPacketType, err = r.U8()
+        if err != nil {
+            return nil, err
+        }
 
-        videoBody = VideoBody{
-            //Tag: flvTag,
-            VideoTagHeader: videoTagHeader,
-            Data: videoData,
+        videoTagHeader.CompositionTime, err = r.I24BE()
+        if err != nil {
+            return nil, err
         }
-    } else {
-        videoData := data[1:]
-        
-        videoBody = VideoBody{
-            //Tag: flvTag,
-            VideoTagHeader: videoTagHeader,
-            Data: videoData,
+    }
+
+    return &VideoBody{
+        VideoTagHeader: videoTagHeader,
+        Data: r.Rest(),
+    }, nil
+}
+
+// decodeExVideo decodes Enhanced RTMP video tag, which has IsExHeader bit set:
+// IsExHeader (1 bit), FrameType (3 bits), PacketType (4 bits), FourCC (4 bytes)
+// and CompositionTime (3 bytes) if PacketType is CodedFrames of AVC/HEVC.
+func decodeExVideo(flags uint8, r *bin.Reader) (*VideoBody, error) {
+    fourCC, err := r.Bytes(4)
+    if err != nil {
+        return nil, err
+    }
+
+    videoTagHeader := VideoTagHeader{
+        IsExHeader: true,
+        FrameType: (flags >> 4) & 0x7,
+        PacketType: flags & 0xf,
+        FourCC: string(fourCC),
+    }
+
+    if videoTagHeader.hasCompositionTime() {
+        videoTagHeader.CompositionTime, err = r.I24BE()
+        if err != nil {
+            return nil, err
         }
     }
 
-    return &videoBody
+    return &VideoBody{
+        VideoTagHeader: videoTagHeader,
+        Data: r.Rest(),
+    }, nil
 }
@@ -0,0 +1,108 @@
+package flv
+
+import (
+    "bytes"
+    "io"
+    "testing"
+)
+
+func TestDecodeAudio(t *testing.T) {
+    tests := []struct {
+        name   string
+        data   []byte
+        header AudioTagHeader
+        body   []byte
+    }{
+        {"MP3", []byte{0x2f, 0xff, 0xfb}, AudioTagHeader{SoundFormat: SoundFormatMP3, SoundRate: 3, SoundSize: 1, SoundType: 1}, []byte{0xff, 0xfb}},
+        {"AAC sequence header", []byte{0xaf, 0x00, 0x12, 0x10}, AudioTagHeader{SoundFormat: SoundFormatAAC, SoundRate: 3, SoundSize: 1, SoundType: 1}, []byte{0x12, 0x10}},
+        {"AAC raw", []byte{0xaf, 0x01}, AudioTagHeader{SoundFormat: SoundFormatAAC, SoundRate: 3, SoundSize: 1, SoundType: 1, AACPacketType: 1}, []byte{}},
+        {"Opus coded frames", []byte{0x91, 'O', 'p', 'u', 's', 0xfc}, AudioTagHeader{SoundFormat: SoundFormatExHeader, SoundType: 1, AudioPacketType: AudioPacketTypeCodedFrames, FourCC: FourCCOpus}, []byte{0xfc}},
+    }
+
+    for _, test := range tests {
+        body, err := DecodeAudio(test.data)
+        if err != nil {
+            t.Errorf("%s: DecodeAudio() = %v", test.name, err)
+            continue
+        }
+
+        if body.AudioTagHeader != test.header || !bytes.Equal(body.Data, test.body) {
+            t.Errorf("%s: DecodeAudio() = %+v, % x, want %+v, % x", test.name, body.AudioTagHeader, body.Data, test.header, test.body)
+        }
+    }
+}
+
+func TestDecodeAudioUnexpectedEOF(t *testing.T) {
+    tests := []struct {
+        name string
+        data []byte
+    }{
+        {"empty", nil},
+        {"AAC without AACPacketType", []byte{0xaf}},
+        {"Enhanced without FourCC", []byte{0x91}},
+        {"Enhanced with 1-byte FourCC", []byte{0x91, 'O'}},
+        {"Enhanced with short FourCC", []byte{0x91, 'O', 'p', 'u'}},
+    }
+
+    for _, test := range tests {
+        if _, err := DecodeAudio(test.data); err != io.ErrUnexpectedEOF {
+            t.Errorf("%s: DecodeAudio(% x) = %v, want %v", test.name, test.data, err, io.ErrUnexpectedEOF)
+        }
+    }
+}
+
+func TestDecodeVideo(t *testing.T) {
+    tests := []struct {
+        name   string
+        data   []byte
+        header VideoTagHeader
+        body   []byte
+    }{
+        {"AVC key frame", []byte{0x17, 0x01, 0x00, 0x00, 0x50, 0x00}, VideoTagHeader{FrameType: FrameTypeKey, CodecID: CodecIDAVC, AVCPacketType: 1, CompositionTime: 80}, []byte{0x00}},
+        {"AVC negative composition time", []byte{0x27, 0x01, 0xff, 0xff, 0xd8}, VideoTagHeader{FrameType: FrameTypeInter, CodecID: CodecIDAVC, AVCPacketType: 1, CompositionTime: -40}, []byte{}},
+        {"Sorenson H.263", []byte{0x22, 0x00}, VideoTagHeader{FrameType: FrameTypeInter, CodecID: 2}, []byte{0x00}},
+        {"Enhanced HEVC coded frames", []byte{0x91, 'h', 'v', 'c', '1', 0x00, 0x00, 0x28, 0x01}, VideoTagHeader{IsExHeader: true, FrameType: FrameTypeKey, PacketType: PacketTypeCodedFrames, FourCC: FourCCHEVC, CompositionTime: 40}, []byte{0x01}},
+        {"Enhanced AVC coded frames X", []byte{0xa3, 'a', 'v', 'c', '1', 0x01}, VideoTagHeader{IsExHeader: true, FrameType: FrameTypeInter, PacketType: PacketTypeCodedFramesX, FourCC: FourCCAVC}, []byte{0x01}},
+        {"Enhanced AV1 coded frames", []byte{0x91, 'a', 'v', '0', '1', 0x12}, VideoTagHeader{IsExHeader: true, FrameType: FrameTypeKey, PacketType: PacketTypeCodedFrames, FourCC: FourCCAV1}, []byte{0x12}},
+    }
+
+    for _, test := range tests {
+        body, err := DecodeVideo(test.data)
+        if err != nil {
+            t.Errorf("%s: DecodeVideo() = %v", test.name, err)
+            continue
+        }
+
+        if body.VideoTagHeader != test.header || !bytes.Equal(body.Data, test.body) {
+            t.Errorf("%s: DecodeVideo() = %+v, % x, want %+v, % x", test.name, body.VideoTagHeader, body.Data, test.header, test.body)
+        }
+
+        if header := body.VideoTagHeader.Encode(); !bytes.Equal(append(header, body.Data...), test.data) {
+            t.Errorf("%s: Encode() = % x, want % x", test.name, header, test.data[:len(test.data)-len(body.Data)])
+        }
+    }
+}
+
+func TestDecodeVideoUnexpectedEOF(t *testing.T) {
+    tests := []struct {
+        name string
+        data []byte
+    }{
+        {"empty", nil},
+        {"AVC without AVCPacketType", []byte{0x17}},
+        {"AVC without composition time", []byte{0x17, 0x01}},
+        {"AVC with 1-byte composition time", []byte{0x17, 0x01, 0x00}},
+        {"AVC with short composition time", []byte{0x17, 0x01, 0x00, 0x00}},
+        {"Enhanced without FourCC", []byte{0x91}},
+        {"Enhanced with 1-byte FourCC", []byte{0x91, 'h'}},
+        {"Enhanced with short FourCC", []byte{0x91, 'h', 'v', 'c'}},
+        {"Enhanced HEVC without composition time", []byte{0x91, 'h', 'v', 'c', '1'}},
+        {"Enhanced AVC with short composition time", []byte{0x91, 'a', 'v', 'c', '1', 0x00, 0x00}},
+    }
+
+    for _, test := range tests {
+        if _, err := DecodeVideo(test.data); err != io.ErrUnexpectedEOF {
+            t.Errorf("%s: DecodeVideo(% x) = %v, want %v", test.name, test.data, err, io.ErrUnexpectedEOF)
+        }
+    }
+}
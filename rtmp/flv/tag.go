@@ -4,24 +4,96 @@ import (
     bin "github.com/frankchang0125/go-live-stream/binary"
 )
 
+// SoundFormat
+const (
+    SoundFormatLinearPCM          = 0
+    SoundFormatADPCM              = 1
+    SoundFormatMP3                = 2
+    SoundFormatLinearPCMLE        = 3
+    SoundFormatNellymoser16kMono  = 4
+    SoundFormatNellymoser8kMono   = 5
+    SoundFormatNellymoser         = 6
+    SoundFormatG711ALaw           = 7
+    SoundFormatG711MuLaw          = 8
+    SoundFormatExHeader           = 9 // Enhanced RTMP, audio codec is specified by FourCC
+    SoundFormatAAC                = 10
+    SoundFormatSpeex              = 11
+    SoundFormatMP38k              = 14
+    SoundFormatDeviceSpecific     = 15
+)
+
+// Sample rates of legacy SoundRate
+var soundRates = []int{5512, 11025, 22050, 44100}
+
+// CodecID
+const (
+    CodecIDAVC  = 7
+    CodecIDHEVC = 12 // Non-standard legacy HEVC, with the same 5-byte header as AVC
+)
+
+// Video FrameType
+const (
+    FrameTypeKey             = 1
+    FrameTypeInter           = 2
+    FrameTypeDisposableInter = 3
+    FrameTypeGeneratedKey    = 4
+    FrameTypeCommand         = 5
+)
+
+// Enhanced RTMP FourCC
+const (
+    FourCCAVC  = "avc1"
+    FourCCHEVC = "hvc1"
+    FourCCAV1  = "av01"
+    FourCCVP9  = "vp09"
+    FourCCOpus = "Opus"
+)
+
+// FourCCs of supported Enhanced RTMP codecs
+var SupportedFourCCs = []string{FourCCAVC, FourCCHEVC, FourCCAV1, FourCCVP9, FourCCOpus}
+
+// Enhanced RTMP video PacketType
+const (
+    PacketTypeSequenceStart        = 0
+    PacketTypeCodedFrames          = 1
+    PacketTypeSequenceEnd          = 2
+    PacketTypeCodedFramesX         = 3 // Coded frames without CompositionTime
+    PacketTypeMetadata             = 4
+    PacketTypeMPEG2TSSequenceStart = 5
+)
+
+// Enhanced RTMP AudioPacketType
+const (
+    AudioPacketTypeSequenceStart = 0
+    AudioPacketTypeCodedFrames   = 1
+    AudioPacketTypeSequenceEnd   = 2
+)
+
 type AudioTagHeader struct {
     SoundFormat     uint8
     SoundRate       uint8
     SoundSize       uint8
     SoundType       uint8
     AACPacketType   uint8
+    AudioPacketType uint8  // Enhanced RTMP only
+    FourCC          string // Enhanced RTMP only
 }
 
 func (header *AudioTagHeader) Encode() []byte {
     var result []byte
 
-    if header.SoundFormat == 10 {
+    if header.SoundFormat == SoundFormatAAC {
         result = make([]byte, 2)
         result[0] = (header.SoundFormat << 4) |
                 (header.SoundRate << 2) |
                 (header.SoundSize << 1) |
                 header.SoundType
         result[1] = header.AACPacketType
+    } else if header.SoundFormat == SoundFormatExHeader {
+        result = make([]byte, 5)
+        result[0] = (header.SoundFormat << 4) |
+                (header.AudioPacketType & 0xf)
+        copy(result[1:], header.FourCC)
     } else {
         result = make([]byte, 1)
         result[0] = (header.SoundFormat << 4) |
@@ -33,15 +105,90 @@ func (header *AudioTagHeader) Encode() []byte {
     return result
 }
 
+// CodecName returns the name of audio codec, FourCC for Enhanced RTMP.
+func (header *AudioTagHeader) CodecName() string {
+    switch header.SoundFormat {
+    case SoundFormatLinearPCM, SoundFormatLinearPCMLE:
+        return "pcm"
+    case SoundFormatADPCM:
+        return "adpcm"
+    case SoundFormatMP3, SoundFormatMP38k:
+        return "mp3"
+    case SoundFormatNellymoser16kMono, SoundFormatNellymoser8kMono, SoundFormatNellymoser:
+        return "nellymoser"
+    case SoundFormatG711ALaw:
+        return "g711a"
+    case SoundFormatG711MuLaw:
+        return "g711u"
+    case SoundFormatExHeader:
+        return header.FourCC
+    case SoundFormatAAC:
+        return "aac"
+    case SoundFormatSpeex:
+        return "speex"
+    }
+
+    return "unknown"
+}
+
+// SampleRate returns the sample rate implied by audio tag header, 0 if it must be
+// parsed from audio data, i.e. AAC and Enhanced RTMP. SoundRate of MP3 is a hint only.
+func (header *AudioTagHeader) SampleRate() int {
+    switch header.SoundFormat {
+    case SoundFormatAAC, SoundFormatExHeader:
+        return 0
+    case SoundFormatNellymoser16kMono, SoundFormatSpeex:
+        return 16000
+    case SoundFormatNellymoser8kMono, SoundFormatG711ALaw, SoundFormatG711MuLaw, SoundFormatMP38k:
+        return 8000
+    }
+
+    return soundRates[header.SoundRate & 0x3]
+}
+
+// Channels returns the number of channels implied by audio tag header, 0 if it must be
+// parsed from audio data, i.e. AAC and Enhanced RTMP.
+func (header *AudioTagHeader) Channels() int {
+    switch header.SoundFormat {
+    case SoundFormatAAC, SoundFormatExHeader:
+        return 0
+    case SoundFormatNellymoser16kMono, SoundFormatNellymoser8kMono, SoundFormatSpeex:
+        return 1
+    }
+
+    return int(header.SoundType) + 1
+}
+
+// IsOpus reports whether audio tag carries Opus, which is only available in Enhanced RTMP.
+func (header *AudioTagHeader) IsOpus() bool {
+    return header.SoundFormat == SoundFormatExHeader && header.FourCC == FourCCOpus
+}
+
+// IsSequenceHeader reports whether audio tag carries decoder configuration,
+// e.g. AAC AudioSpecificConfig or OpusHead.
+func (header *AudioTagHeader) IsSequenceHeader() bool {
+    switch header.SoundFormat {
+    case SoundFormatAAC:
+        return header.AACPacketType == 0
+    case SoundFormatExHeader:
+        return header.AudioPacketType == AudioPacketTypeSequenceStart
+    }
+
+    return false
+}
+
 type AudioBody struct {
     AudioTagHeader
     Data []byte
 }
 
 type VideoTagHeader struct {
+    IsExHeader          bool   // Enhanced RTMP, video codec is specified by FourCC
     FrameType           uint8
     CodecID             uint8
     AVCPacketType       uint8
+    PacketType          uint8  // Enhanced RTMP only
+    FourCC              string // Enhanced RTMP only
     CompositionTime     int32
 }
 
@@ -50,20 +197,89 @@ type VideoBody struct {
     Data []byte
 }
 
+// hasCompositionTime reports whether Enhanced RTMP video tag carries CompositionTime,
+// which only presents in CodedFrames of AVC and HEVC.
+func (header *VideoTagHeader) hasCompositionTime() bool {
+    return header.PacketType == PacketTypeCodedFrames &&
+        (header.FourCC == FourCCAVC || header.FourCC == FourCCHEVC)
+}
+
+// hasAVCHeader reports whether legacy video tag carries AVCPacketType and CompositionTime,
+// which present in AVC and legacy HEVC.
+func (header *VideoTagHeader) hasAVCHeader() bool {
+    return header.CodecID == CodecIDAVC || header.CodecID == CodecIDHEVC
+}
+
+// IsHEVC reports whether video tag carries HEVC, either legacy or Enhanced RTMP.
+func (header *VideoTagHeader) IsHEVC() bool {
+    if header.IsExHeader {
+        return header.FourCC == FourCCHEVC
+    }
+
+    return header.CodecID == CodecIDHEVC
+}
+
+// IsAVC reports whether video tag carries AVC, either legacy or Enhanced RTMP.
+func (header *VideoTagHeader) IsAVC() bool {
+    if header.IsExHeader {
+        return header.FourCC == FourCCAVC
+    }
+
+    return header.CodecID == CodecIDAVC
+}
+
+// IsAV1 reports whether video tag carries AV1, which is only available in Enhanced RTMP.
+func (header *VideoTagHeader) IsAV1() bool {
+    return header.IsExHeader && header.FourCC == FourCCAV1
+}
+
+// IsKeyFrame reports whether video tag is flagged as a key frame.
+func (header *VideoTagHeader) IsKeyFrame() bool {
+    return header.FrameType == FrameTypeKey
+}
+
+// IsSequenceHeader reports whether video tag carries decoder configuration record,
+// e.g. AVC sequence header or HEVC/AV1/VP9 sequence start.
+func (header *VideoTagHeader) IsSequenceHeader() bool {
+    if header.IsExHeader {
+        return header.PacketType == PacketTypeSequenceStart
+    }
+
+    return header.hasAVCHeader() && header.AVCPacketType == 0
+}
+
+// IsCodedFrames reports whether video tag carries coded frames,
+// rather than sequence header, end of sequence or metadata.
+func (header *VideoTagHeader) IsCodedFrames() bool {
+    if header.IsExHeader {
+        return header.PacketType == PacketTypeCodedFrames ||
+            header.PacketType == PacketTypeCodedFramesX
+    }
+
+    return !header.hasAVCHeader() || header.AVCPacketType == 1
+}
+
 func (header *VideoTagHeader) Encode() []byte {
-    var result []byte
+    w := bin.NewWriter(8)
 
-    if header.CodecID == 7 {
-        result = make([]byte, 5)
-        result[0] = header.FrameType << 4 |
-                    header.CodecID
-        result[1] = header.AVCPacketType
-        bin.PutI24BE(result[2:], header.CompositionTime)
+    if header.IsExHeader {
+        w.PutU8(0x80 |
+                (header.FrameType & 0x7) << 4 |
+                (header.PacketType & 0xf))
+        w.PutBytes([]byte(header.FourCC))
+
+        if header.hasCompositionTime() {
+            w.PutI24BE(header.CompositionTime)
+        }
+    } else if header.hasAVCHeader() {
+        w.PutU8(header.FrameType << 4 |
+                header.CodecID)
+        w.PutU8(header.AVCPacketType)
+        w.PutI24BE(header.CompositionTime)
     } else {
-        result = make([]byte, 1)
-        result[0] = header.FrameType << 4 |
-                    header.CodecID
+        w.PutU8(header.FrameType << 4 |
+                header.CodecID)
     }
 
-    return result
-}
\ No newline at end of file
+    return w.Bytes()
+}
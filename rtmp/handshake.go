@@ -1,9 +1,13 @@
 package rtmp
 
 import (
+	"bytes"
+	"crypto/hmac"
 	"crypto/rand"
+	"crypto/sha256"
 	"fmt"
 	"io"
+	"time"
 
 	log "github.com/sirupsen/logrus"
 )
@@ -17,8 +21,42 @@ const (
 	s2Len = 1536
 )
 
+// Complex handshake
+const (
+	digestLen     = 32
+	digestOffsets = 728 // Number of possible digest offsets in each schema
+)
+
+// Digest schemas of complex handshake, which differ in the position of digest in C1/S1
+const (
+	schemaNone    = iota // Simple handshake
+	schemaDigest0        // Digest offset is computed from bytes 8 - 11
+	schemaDigest1        // Digest offset is computed from bytes 772 - 775
+)
+
+// Version of server sent in S1 of complex handshake
+var serverVersion = []byte{0x04, 0x05, 0x00, 0x01}
+
+var genuineKeySuffix = []byte{
+	0xF0, 0xEE, 0xC2, 0x4A, 0x80, 0x68, 0xBE, 0xE8,
+	0x2E, 0x00, 0xD0, 0xD1, 0x02, 0x9E, 0x7E, 0x57,
+	0x6E, 0xEC, 0x5D, 0x2D, 0x29, 0x80, 0x6F, 0xAB,
+	0x93, 0xB8, 0xE6, 0x36, 0xCF, 0xEB, 0x31, 0xAE,
+}
+
+var (
+	genuineFMSKey = append([]byte("Genuine Adobe Flash Media Server 001"), genuineKeySuffix...)
+	genuineFPKey  = append([]byte("Genuine Adobe Flash Player 001"), genuineKeySuffix...)
+)
+
 func (conn *Conn) Handshake() error {
 	log.Info("Handshaking...")
+
+	// The whole handshake must complete within handshake timeout
+	if handshakeTimeout := timeout(conn.server.config.HandshakeTimeout, defaultHandshakeTimeout); handshakeTimeout > 0 {
+		conn.Conn.SetDeadline(time.Now().Add(handshakeTimeout))
+	}
+
 	var S0S1S2 [(s0Len + s1Len + s2Len)]byte
 
 	S0 := S0S1S2[:s0Len]
@@ -45,13 +83,62 @@ func (conn *Conn) Handshake() error {
 	// Version: 3
 	S0[0] = 3
 
+	// Complex handshake is used if client's version is not zero and C1 carries a valid digest
+	schema := schemaNone
+	var clientDigest []byte
+
+	if !bytes.Equal(C1[4:8], []byte{0, 0, 0, 0}) {
+		schema, clientDigest = findDigest(C1, genuineFPKey[:30])
+	}
+
+	if schema == schemaNone {
+		err = simpleHandshake(C1, S1, S2)
+	} else {
+		err = complexHandshake(schema, clientDigest, S1, S2)
+	}
+
+	if err != nil {
+		log.WithField("err", err).Error("Fail to create S1S2 packets.")
+		return err
+	}
+
+	// S0S1S2 ->
+	_, err = conn.Write(S0S1S2[:])
+	if err != nil {
+		log.WithField("err", err).Error("Fail to handshake.")
+		return err
+	}
+
+	// <- C2
+	C2 := make([]byte, c2Len)
+	_, err = io.ReadFull(conn, C2)
+	if err != nil {
+		log.WithField("err", err).Error("Fail to read C2 packet.")
+		return err
+	}
+
+	// Some clients send C2 which does not follow the specification,
+	// thus invalid C2 is logged only
+	if !validateC2(schema, S1, C2) {
+		log.WithField("complex", schema != schemaNone).Warning("C2 does not match S1.")
+	}
+
+	conn.Conn.SetDeadline(time.Time{})
+	conn.handshaked = true
+
+	log.WithField("complex", schema != schemaNone).Info("Handshake complete.")
+
+	return nil
+}
+
+// simpleHandshake creates S1 and S2 of simple handshake.
+func simpleHandshake(C1 []byte, S1 []byte, S2 []byte) error {
 	S1Time := make([]byte, 4)
 	S1Zero := make([]byte, 4)
 	S1Random := make([]byte, 1528)
 
-	_, err = rand.Read(S1Random)
+	_, err := rand.Read(S1Random)
 	if err != nil {
-		log.WithField("err", err).Error("Fail to create S1 random.")
 		return err
 	}
 
@@ -67,22 +154,87 @@ func (conn *Conn) Handshake() error {
 
 	copy(S2, append(append(S2Time1, S2Time2...), S2Random...))
 
-	// S0S1S2 ->
-	_, err = conn.Write(S0S1S2[:])
+	return nil
+}
+
+// complexHandshake creates S1 and S2 of complex handshake with the same digest schema as C1.
+func complexHandshake(schema int, clientDigest []byte, S1 []byte, S2 []byte) error {
+	_, err := rand.Read(S1)
 	if err != nil {
-		log.WithField("err", err).Error("Fail to handshake.")
 		return err
 	}
 
-	// <- C2
-	C2 := make([]byte, c2Len)
-	_, err = io.ReadFull(conn, C2)
+	copy(S1[0:4], []byte{0, 0, 0, 0})
+	copy(S1[4:8], serverVersion)
+
+	// S1 digest is keyed with the first 36 bytes of FMS key
+	offset := digestOffset(schema, S1)
+	copy(S1[offset:], calcDigest(S1, offset, genuineFMSKey[:36]))
+
+	// S2 digest is keyed with the digest of C1 signed by the whole FMS key
+	_, err = rand.Read(S2)
 	if err != nil {
-		log.WithField("err", err).Error("Fail to read C2 packet.")
 		return err
 	}
 
-	log.Info("Handshake complete.")
+	copy(S2[s2Len-digestLen:], s2Digest(genuineFMSKey, clientDigest, S2))
 
 	return nil
 }
+
+// validateC2 reports whether C2 echoes S1 in simple handshake,
+// or carries the digest of S1 in complex handshake.
+func validateC2(schema int, S1 []byte, C2 []byte) bool {
+	if schema == schemaNone {
+		return bytes.Equal(C2[8:], S1[8:])
+	}
+
+	serverDigest := S1[digestOffset(schema, S1):][:digestLen]
+	return hmac.Equal(C2[c2Len-digestLen:], s2Digest(genuineFPKey, serverDigest, C2))
+}
+
+// findDigest finds the digest schema of C1 by validating digest of each schema,
+// returns schemaNone if C1 carries no valid digest.
+func findDigest(C1 []byte, key []byte) (int, []byte) {
+	for _, schema := range []int{schemaDigest1, schemaDigest0} {
+		offset := digestOffset(schema, C1)
+		digest := C1[offset : offset+digestLen]
+
+		if hmac.Equal(digest, calcDigest(C1, offset, key)) {
+			return schema, digest
+		}
+	}
+
+	return schemaNone, nil
+}
+
+// digestOffset returns the offset of digest in C1/S1 of schema.
+func digestOffset(schema int, buf []byte) int {
+	base := 8
+	if schema == schemaDigest1 {
+		base = 772
+	}
+
+	offset := int(buf[base]) + int(buf[base+1]) + int(buf[base+2]) + int(buf[base+3])
+	return offset%digestOffsets + base + 4
+}
+
+// calcDigest returns HMAC-SHA256 of buf excluding the digest at offset.
+func calcDigest(buf []byte, offset int, key []byte) []byte {
+	mac := hmac.New(sha256.New, key)
+	mac.Write(buf[:offset])
+	mac.Write(buf[offset+digestLen:])
+	return mac.Sum(nil)
+}
+
+// s2Digest returns the digest placed at the end of S2/C2,
+// keyed with the digest of C1/S1 signed by key.
+func s2Digest(key []byte, peerDigest []byte, buf []byte) []byte {
+	mac := hmac.New(sha256.New, key)
+	mac.Write(peerDigest)
+	tempKey := mac.Sum(nil)
+
+	mac = hmac.New(sha256.New, tempKey)
+	mac.Write(buf[:len(buf)-digestLen])
+	return mac.Sum(nil)
+}
@@ -0,0 +1,162 @@
+package rtmp
+
+import (
+	"bytes"
+	"crypto/rand"
+	"io"
+	"net"
+	"testing"
+)
+
+// clientC1 returns C1 of complex handshake with digest of schema keyed by Flash Player key.
+func clientC1(t *testing.T, schema int) ([]byte, []byte) {
+	C1 := make([]byte, c1Len)
+	if _, err := rand.Read(C1); err != nil {
+		t.Fatalf("rand.Read() = %v", err)
+	}
+
+	copy(C1[0:4], []byte{0, 0, 0, 0})
+	copy(C1[4:8], []byte{0x80, 0x00, 0x07, 0x02})
+
+	offset := digestOffset(schema, C1)
+	digest := calcDigest(C1, offset, genuineFPKey[:30])
+	copy(C1[offset:], digest)
+
+	return C1, digest
+}
+
+func TestComplexHandshake(t *testing.T) {
+	for _, schema := range []int{schemaDigest0, schemaDigest1} {
+		C1, digest := clientC1(t, schema)
+
+		found, clientDigest := findDigest(C1, genuineFPKey[:30])
+		if found != schema || !bytes.Equal(clientDigest, digest) {
+			t.Errorf("schema %d: findDigest() = %d, %x, want %d, %x", schema, found, clientDigest, schema, digest)
+			continue
+		}
+
+		S1 := make([]byte, s1Len)
+		S2 := make([]byte, s2Len)
+
+		if err := complexHandshake(schema, clientDigest, S1, S2); err != nil {
+			t.Fatalf("schema %d: complexHandshake() = %v", schema, err)
+		}
+
+		if !bytes.Equal(S1[4:8], serverVersion) {
+			t.Errorf("schema %d: S1 version = % x, want % x", schema, S1[4:8], serverVersion)
+		}
+
+		// S1 digest is verified by client with FMS key in the same schema as C1
+		serverSchema, serverDigest := findDigest(S1, genuineFMSKey[:36])
+		if serverSchema != schema {
+			t.Errorf("schema %d: S1 digest found in schema %d", schema, serverSchema)
+			continue
+		}
+
+		// S2 digest is keyed with C1 digest signed by the whole FMS key
+		if !bytes.Equal(S2[s2Len-digestLen:], s2Digest(genuineFMSKey, digest, S2)) {
+			t.Errorf("schema %d: S2 digest does not verify", schema)
+		}
+
+		// C2 created by client is keyed with S1 digest signed by the whole FP key
+		C2 := make([]byte, c2Len)
+		rand.Read(C2)
+		copy(C2[c2Len-digestLen:], s2Digest(genuineFPKey, serverDigest, C2))
+
+		if !validateC2(schema, S1, C2) {
+			t.Errorf("schema %d: validateC2() of valid C2 = false", schema)
+		}
+
+		C2[0]++
+		if validateC2(schema, S1, C2) {
+			t.Errorf("schema %d: validateC2() of altered C2 = true", schema)
+		}
+	}
+
+	// C1 without valid digest
+	C1, _ := clientC1(t, schemaDigest0)
+	C1[digestOffset(schemaDigest0, C1)]++
+
+	if schema, _ := findDigest(C1, genuineFPKey[:30]); schema != schemaNone {
+		t.Errorf("findDigest() of invalid digest = %d, want %d", schema, schemaNone)
+	}
+}
+
+// handshake performs handshake of client sending C1 with server, returns S0S1S2 received.
+func handshake(t *testing.T, C1 []byte, C2 func(S1 []byte) []byte) []byte {
+	server, err := NewRTMPServer(&Config{})
+	if err != nil {
+		t.Fatalf("NewRTMPServer() = %v", err)
+	}
+
+	serverConn, clientConn := net.Pipe()
+	defer clientConn.Close()
+
+	done := make(chan error, 1)
+	go func() {
+		done <- NewConn(serverConn, server).Handshake()
+	}()
+
+	clientConn.Write(append([]byte{3}, C1...))
+
+	S0S1S2 := make([]byte, s0Len+s1Len+s2Len)
+	if _, err := io.ReadFull(clientConn, S0S1S2); err != nil {
+		t.Fatalf("Reading S0S1S2 = %v", err)
+	}
+
+	clientConn.Write(C2(S0S1S2[s0Len : s0Len+s1Len]))
+
+	if err := <-done; err != nil {
+		t.Fatalf("Handshake() = %v", err)
+	}
+
+	return S0S1S2
+}
+
+func TestHandshake(t *testing.T) {
+	for _, schema := range []int{schemaDigest0, schemaDigest1} {
+		C1, digest := clientC1(t, schema)
+
+		S0S1S2 := handshake(t, C1, func(S1 []byte) []byte {
+			_, serverDigest := findDigest(S1, genuineFMSKey[:36])
+			C2 := make([]byte, c2Len)
+			copy(C2[c2Len-digestLen:], s2Digest(genuineFPKey, serverDigest, C2))
+			return C2
+		})
+
+		S1 := S0S1S2[s0Len : s0Len+s1Len]
+		S2 := S0S1S2[s0Len+s1Len:]
+
+		if found, _ := findDigest(S1, genuineFMSKey[:36]); S0S1S2[0] != 3 || found != schema {
+			t.Errorf("schema %d: S0 = %d, S1 digest found in schema %d", schema, S0S1S2[0], found)
+		}
+
+		if !bytes.Equal(S2[s2Len-digestLen:], s2Digest(genuineFMSKey, digest, S2)) {
+			t.Errorf("schema %d: S2 digest does not verify", schema)
+		}
+	}
+}
+
+func TestHandshakeZeroVersion(t *testing.T) {
+	// C1 of zero version falls back to simple handshake even if it carries a valid digest
+	C1, _ := clientC1(t, schemaDigest0)
+	copy(C1[4:8], []byte{0, 0, 0, 0})
+	offset := digestOffset(schemaDigest0, C1)
+	copy(C1[offset:], calcDigest(C1, offset, genuineFPKey[:30]))
+
+	S0S1S2 := handshake(t, C1, func(S1 []byte) []byte {
+		return append([]byte(nil), S1...)
+	})
+
+	S1 := S0S1S2[s0Len : s0Len+s1Len]
+	S2 := S0S1S2[s0Len+s1Len:]
+
+	// Simple handshake sends zero version in S1 and echoes C1 in S2
+	if !bytes.Equal(S1[4:8], []byte{0, 0, 0, 0}) {
+		t.Errorf("S1 version = % x, want zero", S1[4:8])
+	}
+
+	if !bytes.Equal(S2[8:], C1[8:]) {
+		t.Error("S2 does not echo C1")
+	}
+}
@@ -0,0 +1,187 @@
+package rtmp
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"strconv"
+	"time"
+
+	log "github.com/sirupsen/logrus"
+)
+
+// Hook actions
+const (
+	HookOnConnect   = "on_connect"
+	HookOnPublish   = "on_publish"
+	HookOnUnpublish = "on_unpublish"
+	HookOnPlay      = "on_play"
+	HookOnStop      = "on_stop"
+)
+
+const (
+	defaultHookTimeout  = 5 * time.Second
+	defaultAdmitTimeout = 10 * time.Second
+	hookRetryInterval   = 500 * time.Millisecond
+)
+
+var ErrHookRejected = errors.New("Rejected by hook")
+
+// HookEvent is the JSON body POSTed to hook URLs.
+type HookEvent struct {
+	Action   string `json:"action"`
+	ClientID uint64 `json:"client_id"`
+	IP       string `json:"ip"`
+	VHost    string `json:"vhost"`
+	App      string `json:"app"`
+	TcURL    string `json:"tcUrl,omitempty"`
+	PageURL  string `json:"pageUrl,omitempty"`
+	Stream   string `json:"stream,omitempty"`
+	Param    string `json:"param,omitempty"` // Query string split off the stream name
+}
+
+// Hooks calls the HTTP callbacks configured on a virtual host or application.
+type Hooks struct {
+	config       *HooksConfig
+	client       *http.Client
+	admitTimeout time.Duration
+}
+
+func NewHooks(config *HooksConfig) *Hooks {
+	timeout := config.Timeout.Duration
+	if timeout <= 0 {
+		timeout = defaultHookTimeout
+	}
+
+	admitTimeout := config.AdmitTimeout.Duration
+	if admitTimeout <= 0 {
+		admitTimeout = defaultAdmitTimeout
+	}
+
+	return &Hooks{
+		config:       config,
+		client:       &http.Client{Timeout: timeout},
+		admitTimeout: admitTimeout,
+	}
+}
+
+func (h *Hooks) urls(action string) []string {
+	switch action {
+	case HookOnConnect:
+		return h.config.OnConnect
+	case HookOnPublish:
+		return h.config.OnPublish
+	case HookOnUnpublish:
+		return h.config.OnUnpublish
+	case HookOnPlay:
+		return h.config.OnPlay
+	case HookOnStop:
+		return h.config.OnStop
+	default:
+		return nil
+	}
+}
+
+// Admit calls the admission hooks of event's action (on_connect, on_publish, on_play) in order,
+// the action is rejected if any hook responds with non-2xx status code.
+// Unreachable hooks reject the action unless the hooks are configured to fail open.
+//
+// Admit blocks the caller (the connection's read goroutine) until all hooks respond,
+// the hooks and their retries are cut off once the admission timeout elapses.
+func (h *Hooks) Admit(event *HookEvent) error {
+	if h == nil {
+		return nil
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), h.admitTimeout)
+	defer cancel()
+
+	for _, url := range h.urls(event.Action) {
+		err := h.post(ctx, url, event)
+		if err == nil {
+			continue
+		}
+
+		if err == ErrHookRejected || !h.config.FailOpen {
+			return err
+		}
+
+		log.WithFields(log.Fields{
+			"action": event.Action,
+			"url":    url,
+			"err":    err,
+		}).Warning("Hook unreachable, admit action as hooks fail open.")
+	}
+
+	return nil
+}
+
+// Notify calls the notification hooks of event's action (on_unpublish, on_stop)
+// in background, responses of the hooks are ignored.
+func (h *Hooks) Notify(event *HookEvent) {
+	if h == nil {
+		return
+	}
+
+	for _, url := range h.urls(event.Action) {
+		go func(url string) {
+			err := h.post(context.Background(), url, event)
+			if err != nil {
+				log.WithFields(log.Fields{
+					"action": event.Action,
+					"url":    url,
+					"err":    err,
+				}).Warning("Fail to notify hook.")
+			}
+		}(url)
+	}
+}
+
+// post POSTs event to url, retrying on network errors until ctx is done.
+func (h *Hooks) post(ctx context.Context, url string, event *HookEvent) error {
+	body, err := json.Marshal(event)
+	if err != nil {
+		return err
+	}
+
+	for attempt := 0; ; attempt++ {
+		var req *http.Request
+		req, err = http.NewRequest(http.MethodPost, url, bytes.NewReader(body))
+		if err != nil {
+			return err
+		}
+		req.Header.Set("Content-Type", "application/json")
+
+		var resp *http.Response
+		resp, err = h.client.Do(req.WithContext(ctx))
+		if err == nil {
+			io.Copy(ioutil.Discard, resp.Body)
+			resp.Body.Close()
+
+			if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+				log.WithFields(log.Fields{
+					"action": event.Action,
+					"url":    url,
+					"status": strconv.Itoa(resp.StatusCode),
+				}).Info("Action rejected by hook.")
+				return ErrHookRejected
+			}
+
+			return nil
+		}
+
+		if attempt >= h.config.Retries {
+			return err
+		}
+
+		select {
+		case <-time.After(hookRetryInterval):
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+	}
+}
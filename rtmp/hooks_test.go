@@ -0,0 +1,232 @@
+package rtmp
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// newHookServer returns a hook server responding with status, counting requests received.
+func newHookServer(status int, requests *int32) *httptest.Server {
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(requests, 1)
+		w.WriteHeader(status)
+	}))
+}
+
+// unreachableURL returns the URL of a closed hook server.
+func unreachableURL() string {
+	server := httptest.NewServer(http.NotFoundHandler())
+	server.Close()
+
+	return server.URL
+}
+
+func TestHooksAdmit(t *testing.T) {
+	var received HookEvent
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost || r.Header.Get("Content-Type") != "application/json" {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+
+		json.NewDecoder(r.Body).Decode(&received)
+	}))
+	defer server.Close()
+
+	hooks := NewHooks(&HooksConfig{OnPublish: []string{server.URL}})
+
+	event := &HookEvent{
+		Action:   HookOnPublish,
+		ClientID: 1,
+		IP:       "127.0.0.1",
+		VHost:    DefaultVHost,
+		App:      "live",
+		Stream:   "mylive",
+		Param:    "key=s3cr3t",
+	}
+
+	if err := hooks.Admit(event); err != nil {
+		t.Fatalf("Admit() = %v", err)
+	}
+
+	if received != *event {
+		t.Errorf("hook received %+v, want %+v", received, *event)
+	}
+
+	// Actions without hooks are admitted
+	if err := hooks.Admit(&HookEvent{Action: HookOnPlay}); err != nil {
+		t.Errorf("Admit() of action without hooks = %v", err)
+	}
+
+	var nilHooks *Hooks
+	if err := nilHooks.Admit(event); err != nil {
+		t.Errorf("Admit() of nil hooks = %v", err)
+	}
+}
+
+func TestHooksRejected(t *testing.T) {
+	for _, status := range []int{http.StatusMovedPermanently, http.StatusForbidden, http.StatusInternalServerError} {
+		var rejecting, next int32
+		rejectingServer := newHookServer(status, &rejecting)
+		nextServer := newHookServer(http.StatusOK, &next)
+
+		// Rejection is not retried and rejects the action even if hooks fail open
+		hooks := NewHooks(&HooksConfig{
+			OnConnect: []string{rejectingServer.URL, nextServer.URL},
+			Retries:   2,
+			FailOpen:  true,
+		})
+
+		if err := hooks.Admit(&HookEvent{Action: HookOnConnect}); err != ErrHookRejected {
+			t.Errorf("status %d: Admit() = %v, want %v", status, err, ErrHookRejected)
+		}
+
+		if n, m := atomic.LoadInt32(&rejecting), atomic.LoadInt32(&next); n != 1 || m != 0 {
+			t.Errorf("status %d: hooks called %d, %d times, want 1, 0", status, n, m)
+		}
+
+		rejectingServer.Close()
+		nextServer.Close()
+	}
+}
+
+func TestHooksRetries(t *testing.T) {
+	var requests int32
+
+	// Connection of the first request is closed without response
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if atomic.AddInt32(&requests, 1) == 1 {
+			conn, _, _ := w.(http.Hijacker).Hijack()
+			conn.Close()
+		}
+	}))
+	defer server.Close()
+
+	hooks := NewHooks(&HooksConfig{OnPlay: []string{server.URL}, Retries: 1})
+
+	if err := hooks.Admit(&HookEvent{Action: HookOnPlay}); err != nil {
+		t.Errorf("Admit() = %v", err)
+	}
+
+	if n := atomic.LoadInt32(&requests); n != 2 {
+		t.Errorf("hook called %d times, want 2", n)
+	}
+
+	// Unreachable hooks reject the action after retries
+	hooks = NewHooks(&HooksConfig{OnPlay: []string{unreachableURL()}, Retries: 1})
+
+	if err := hooks.Admit(&HookEvent{Action: HookOnPlay}); err == nil || err == ErrHookRejected {
+		t.Errorf("Admit() = %v, want network error", err)
+	}
+}
+
+func TestHooksTimeout(t *testing.T) {
+	release := make(chan bool)
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		<-release
+	}))
+	defer server.Close()
+	defer close(release)
+
+	config := &HooksConfig{
+		OnPublish: []string{server.URL},
+		Timeout:   Duration{50 * time.Millisecond},
+	}
+
+	start := time.Now()
+
+	if err := NewHooks(config).Admit(&HookEvent{Action: HookOnPublish}); err == nil || err == ErrHookRejected {
+		t.Errorf("Admit() = %v, want timeout error", err)
+	}
+
+	if elapsed := time.Since(start); elapsed > time.Second {
+		t.Errorf("Admit() took %v with timeout of %v", elapsed, config.Timeout.Duration)
+	}
+
+	// Hooks failing open admit the action on timeout
+	config.FailOpen = true
+
+	if err := NewHooks(config).Admit(&HookEvent{Action: HookOnPublish}); err != nil {
+		t.Errorf("Admit() failing open = %v", err)
+	}
+}
+
+func TestHooksAdmitTimeout(t *testing.T) {
+	release := make(chan bool)
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		<-release
+	}))
+	defer server.Close()
+	defer close(release)
+
+	// Hooks and retries of an action are cut off by the admission timeout
+	hooks := NewHooks(&HooksConfig{
+		OnConnect:    []string{server.URL, server.URL},
+		Timeout:      Duration{time.Second},
+		AdmitTimeout: Duration{100 * time.Millisecond},
+		Retries:      3,
+	})
+
+	start := time.Now()
+
+	if err := hooks.Admit(&HookEvent{Action: HookOnConnect}); err == nil || err == ErrHookRejected {
+		t.Errorf("Admit() = %v, want timeout error", err)
+	}
+
+	if elapsed := time.Since(start); elapsed > time.Second {
+		t.Errorf("Admit() took %v with admission timeout of 100ms", elapsed)
+	}
+}
+
+func TestHooksFailOpen(t *testing.T) {
+	var requests int32
+	server := newHookServer(http.StatusOK, &requests)
+	defer server.Close()
+
+	// Unreachable hook is skipped and the following hooks are still called
+	hooks := NewHooks(&HooksConfig{
+		OnConnect: []string{unreachableURL(), server.URL},
+		FailOpen:  true,
+	})
+
+	if err := hooks.Admit(&HookEvent{Action: HookOnConnect}); err != nil {
+		t.Errorf("Admit() = %v", err)
+	}
+
+	if n := atomic.LoadInt32(&requests); n != 1 {
+		t.Errorf("hook called %d times, want 1", n)
+	}
+}
+
+func TestHooksNotify(t *testing.T) {
+	events := make(chan HookEvent, 1)
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var event HookEvent
+		json.NewDecoder(r.Body).Decode(&event)
+		events <- event
+
+		// Response of notification hooks is ignored
+		w.WriteHeader(http.StatusForbidden)
+	}))
+	defer server.Close()
+
+	hooks := NewHooks(&HooksConfig{OnStop: []string{server.URL}})
+	hooks.Notify(&HookEvent{Action: HookOnStop, Stream: "mylive"})
+
+	select {
+	case event := <-events:
+		if event.Action != HookOnStop || event.Stream != "mylive" {
+			t.Errorf("hook received %+v", event)
+		}
+	case <-time.After(5 * time.Second):
+		t.Error("Notification hook not called")
+	}
+}
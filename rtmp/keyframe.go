@@ -0,0 +1,94 @@
+package rtmp
+
+import (
+	"github.com/frankchang0125/go-live-stream/codec/av1"
+	"github.com/frankchang0125/go-live-stream/codec/h264"
+	"github.com/frankchang0125/go-live-stream/codec/hevc"
+	"github.com/frankchang0125/go-live-stream/rtmp/flv"
+)
+
+// keyFrameAnalyser derives random access points of published video from coded frames,
+// since FrameType is not reliable: some encoders mark IDR frames as inter frames,
+// others mark every frame as key frame.
+type keyFrameAnalyser struct {
+	naluLengthSize int // Size of NAL unit length prefix from the latest AVC/HEVC sequence header
+
+	// Number of coded frames whose FrameType disagrees with the coded frames
+	falseKeyFrames  uint64 // Flagged as key frame but not a random access point
+	missedKeyFrames uint64 // Random access point not flagged as key frame
+}
+
+func newKeyFrameAnalyser() *keyFrameAnalyser {
+	return &keyFrameAnalyser{
+		naluLengthSize: 4,
+	}
+}
+
+// analyse reports whether video is a random access point, analysed is false if
+// video is not coded frames or codec is not supported, in which case FrameType is trusted.
+func (a *keyFrameAnalyser) analyse(video *flv.VideoBody) (keyFrame bool, analysed bool, err error) {
+	if video.IsSequenceHeader() {
+		a.updateSequenceHeader(video)
+		return video.IsKeyFrame(), false, nil
+	}
+
+	if !video.IsCodedFrames() {
+		return video.IsKeyFrame(), false, nil
+	}
+
+	switch {
+	case video.IsAVC():
+		keyFrame, err = h264.HasIDR(video.Data, a.naluLengthSize)
+	case video.IsHEVC():
+		keyFrame, err = hasIRAP(video.Data, a.naluLengthSize)
+	case video.IsAV1():
+		keyFrame, err = av1.IsKeyFrame(video.Data)
+	default:
+		return video.IsKeyFrame(), false, nil
+	}
+
+	if err != nil {
+		return video.IsKeyFrame(), false, err
+	}
+
+	if keyFrame && !video.IsKeyFrame() {
+		a.missedKeyFrames++
+	} else if !keyFrame && video.IsKeyFrame() {
+		a.falseKeyFrames++
+	}
+
+	return keyFrame, true, nil
+}
+
+// updateSequenceHeader updates NAL unit length size from AVC/HEVC sequence header.
+func (a *keyFrameAnalyser) updateSequenceHeader(video *flv.VideoBody) {
+	switch {
+	case video.IsAVC():
+		record, err := h264.ParseAVCDecoderConfigurationRecord(video.Data)
+		if err == nil {
+			a.naluLengthSize = record.NALULengthSize
+		}
+	case video.IsHEVC():
+		lengthSize, err := hevc.ParseNALULengthSize(video.Data)
+		if err == nil {
+			a.naluLengthSize = lengthSize
+		}
+	}
+}
+
+// hasIRAP reports whether HEVC coded frame contains an IRAP NAL unit,
+// HEVC coded frame is length prefixed the same way as AVC.
+func hasIRAP(data []byte, lengthSize int) (bool, error) {
+	nalus, err := h264.SplitAVCC(data, lengthSize)
+	if err != nil {
+		return false, err
+	}
+
+	for _, nalu := range nalus {
+		if hevc.TypeOf(nalu).IsIRAP() {
+			return true, nil
+		}
+	}
+
+	return false, nil
+}
@@ -0,0 +1,181 @@
+package rtmp
+
+import (
+	"testing"
+
+	"github.com/frankchang0125/go-live-stream/codec/h264"
+	"github.com/frankchang0125/go-live-stream/rtmp/flv"
+)
+
+var (
+	testAVCIDR    = []byte{0x65, 0x88, 0x84, 0x00, 0x33}
+	testAVCNonIDR = []byte{0x41, 0x9a, 0x02, 0x04}
+	testAVCSEI    = []byte{0x06, 0x05, 0x01, 0x00, 0x80}
+)
+
+// avcFrame returns legacy AVC coded frames of NAL units with 4-byte length prefix.
+func avcFrame(frameType uint8, nalus ...[]byte) *flv.VideoBody {
+	data, _ := h264.JoinAVCC(nalus, 4)
+
+	return &flv.VideoBody{
+		VideoTagHeader: flv.VideoTagHeader{FrameType: frameType, CodecID: flv.CodecIDAVC, AVCPacketType: 1},
+		Data:           data,
+	}
+}
+
+// exFrame returns Enhanced RTMP coded frames of fourCC.
+func exFrame(fourCC string, frameType uint8, data []byte) *flv.VideoBody {
+	return &flv.VideoBody{
+		VideoTagHeader: flv.VideoTagHeader{IsExHeader: true, FrameType: frameType, PacketType: flv.PacketTypeCodedFrames, FourCC: fourCC},
+		Data:           data,
+	}
+}
+
+func TestKeyFrameAnalyserAVC(t *testing.T) {
+	a := newKeyFrameAnalyser()
+
+	tests := []struct {
+		name     string
+		video    *flv.VideoBody
+		keyFrame bool
+	}{
+		{"IDR flagged as key frame", avcFrame(flv.FrameTypeKey, testAVCSEI, testAVCIDR), true},
+		{"non-IDR flagged as inter frame", avcFrame(flv.FrameTypeInter, testAVCNonIDR), false},
+		{"IDR flagged as inter frame", avcFrame(flv.FrameTypeInter, testAVCSEI, testAVCIDR), true},
+		{"non-IDR flagged as key frame", avcFrame(flv.FrameTypeKey, testAVCNonIDR), false},
+		{"IDR flagged as disposable inter frame", avcFrame(flv.FrameTypeDisposableInter, testAVCIDR), true},
+	}
+
+	for _, test := range tests {
+		keyFrame, analysed, err := a.analyse(test.video)
+		if err != nil || !analysed || keyFrame != test.keyFrame {
+			t.Errorf("%s: analyse() = %v, %v, %v, want %v, true, nil", test.name, keyFrame, analysed, err, test.keyFrame)
+		}
+	}
+
+	if a.missedKeyFrames != 2 || a.falseKeyFrames != 1 {
+		t.Errorf("missed, false key frames = %d, %d, want 2, 1", a.missedKeyFrames, a.falseKeyFrames)
+	}
+}
+
+func TestKeyFrameAnalyserAllKeyFrames(t *testing.T) {
+	a := newKeyFrameAnalyser()
+
+	// Encoder flagging every frame as key frame, with an IDR every 4 frames
+	for i := 0; i < 12; i++ {
+		nalu := testAVCNonIDR
+		if i%4 == 0 {
+			nalu = testAVCIDR
+		}
+
+		keyFrame, _, err := a.analyse(avcFrame(flv.FrameTypeKey, nalu))
+		if err != nil || keyFrame != (i%4 == 0) {
+			t.Errorf("frame %d: analyse() = %v, %v, want %v", i, keyFrame, err, i%4 == 0)
+		}
+	}
+
+	if a.falseKeyFrames != 9 || a.missedKeyFrames != 0 {
+		t.Errorf("false, missed key frames = %d, %d, want 9, 0", a.falseKeyFrames, a.missedKeyFrames)
+	}
+}
+
+func TestKeyFrameAnalyserNALULengthSize(t *testing.T) {
+	a := newKeyFrameAnalyser()
+
+	// AVC sequence header with 2-byte NAL unit length
+	sps := []byte{0x67, 0x42, 0xc0, 0x0d, 0x8c, 0x8d, 0x40, 0xa0, 0xfd, 0x00, 0xf1, 0x02, 0x24, 0x50}
+	record := append([]byte{0x01, 0x42, 0xc0, 0x0d, 0xfd, 0xe1, 0x00, byte(len(sps))}, sps...)
+	record = append(record, 0x01, 0x00, 0x02, 0x68, 0xce)
+
+	sequenceHeader := &flv.VideoBody{
+		VideoTagHeader: flv.VideoTagHeader{FrameType: flv.FrameTypeKey, CodecID: flv.CodecIDAVC, AVCPacketType: 0},
+		Data:           record,
+	}
+
+	if keyFrame, analysed, _ := a.analyse(sequenceHeader); !keyFrame || analysed {
+		t.Errorf("analyse() of sequence header = %v, %v, want true, false", keyFrame, analysed)
+	}
+
+	if a.naluLengthSize != 2 {
+		t.Fatalf("NAL unit length size = %d, want 2", a.naluLengthSize)
+	}
+
+	data, _ := h264.JoinAVCC([][]byte{testAVCIDR}, 2)
+	video := avcFrame(flv.FrameTypeInter)
+	video.Data = data
+
+	if keyFrame, analysed, err := a.analyse(video); !keyFrame || !analysed || err != nil {
+		t.Errorf("analyse() of 2-byte length IDR = %v, %v, %v, want true, true, nil", keyFrame, analysed, err)
+	}
+
+	// Frames failed to parse fall back to FrameType
+	video.Data = []byte{0x00, 0x10, 0x65}
+
+	if keyFrame, analysed, err := a.analyse(video); keyFrame || analysed || err == nil {
+		t.Errorf("analyse() of truncated frame = %v, %v, %v, want false, false, error", keyFrame, analysed, err)
+	}
+}
+
+func TestKeyFrameAnalyserHEVC(t *testing.T) {
+	a := newKeyFrameAnalyser()
+
+	// NAL unit header: forbidden_zero_bit, nal_unit_type (6 bits), nuh_layer_id, nuh_temporal_id_plus1
+	for typ := byte(0); typ < 32; typ++ {
+		data, _ := h264.JoinAVCC([][]byte{{typ << 1, 0x01, 0xaf}}, 4)
+
+		// BLA, IDR, CRA and reserved IRAP types (16 - 23)
+		want := typ >= 16 && typ <= 23
+
+		keyFrame, analysed, err := a.analyse(exFrame(flv.FourCCHEVC, flv.FrameTypeInter, data))
+		if err != nil || !analysed || keyFrame != want {
+			t.Errorf("NAL unit type %d: analyse() = %v, %v, %v, want %v", typ, keyFrame, analysed, err, want)
+		}
+	}
+
+	// IDR_W_RADL after prefix SEI
+	data, _ := h264.JoinAVCC([][]byte{{0x4e, 0x01, 0x05}, {0x26, 0x01, 0xaf}}, 4)
+
+	if keyFrame, _, err := a.analyse(exFrame(flv.FourCCHEVC, flv.FrameTypeKey, data)); !keyFrame || err != nil {
+		t.Errorf("analyse() of IDR after SEI = %v, %v, want true", keyFrame, err)
+	}
+}
+
+func TestKeyFrameAnalyserAV1(t *testing.T) {
+	a := newKeyFrameAnalyser()
+
+	sequenceHeader := []byte{0x0a, 0x0b, 0x00, 0x00, 0x00, 0x24, 0xc4, 0xff, 0xdf, 0x00, 0x68, 0x02, 0x10}
+	keyFrame := append(append([]byte{0x12, 0x00}, sequenceHeader...), 0x32, 0x02, 0x10, 0x00)
+	interFrame := []byte{0x12, 0x00, 0x32, 0x02, 0x30, 0x00}
+
+	if got, analysed, err := a.analyse(exFrame(flv.FourCCAV1, flv.FrameTypeInter, keyFrame)); !got || !analysed || err != nil {
+		t.Errorf("analyse() of key frame = %v, %v, %v, want true, true, nil", got, analysed, err)
+	}
+
+	if got, _, err := a.analyse(exFrame(flv.FourCCAV1, flv.FrameTypeKey, interFrame)); got || err != nil {
+		t.Errorf("analyse() of inter frame = %v, %v, want false", got, err)
+	}
+
+	if a.missedKeyFrames != 1 || a.falseKeyFrames != 1 {
+		t.Errorf("missed, false key frames = %d, %d, want 1, 1", a.missedKeyFrames, a.falseKeyFrames)
+	}
+}
+
+func TestKeyFrameAnalyserTrustFrameType(t *testing.T) {
+	a := newKeyFrameAnalyser()
+
+	tests := []struct {
+		name  string
+		video *flv.VideoBody
+	}{
+		{"VP9", exFrame(flv.FourCCVP9, flv.FrameTypeKey, []byte{0x82, 0x49})},
+		{"Sorenson H.263", &flv.VideoBody{VideoTagHeader: flv.VideoTagHeader{FrameType: flv.FrameTypeKey, CodecID: 2}}},
+		{"end of sequence", &flv.VideoBody{VideoTagHeader: flv.VideoTagHeader{FrameType: flv.FrameTypeKey, CodecID: flv.CodecIDAVC, AVCPacketType: 2}}},
+	}
+
+	for _, test := range tests {
+		keyFrame, analysed, err := a.analyse(test.video)
+		if !keyFrame || analysed || err != nil {
+			t.Errorf("%s: analyse() = %v, %v, %v, want true, false, nil", test.name, keyFrame, analysed, err)
+		}
+	}
+}
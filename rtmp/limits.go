@@ -0,0 +1,118 @@
+package rtmp
+
+import (
+	"errors"
+)
+
+// Default limits, used if not configured
+const (
+	defaultMaxChunkSize     = 64 * 1024
+	defaultMaxChunkStreams  = 64
+	defaultMaxBufferedBytes = 64 * 1024 * 1024
+)
+
+// Default maximum message length of each message type
+var defaultMaxMessageSizes = map[string]int{
+	"control":      64,
+	"command":      64 * 1024,
+	"data":         1024 * 1024,
+	"sharedObject": 64 * 1024,
+	"audio":        1024 * 1024,
+	"video":        16 * 1024 * 1024,
+	"aggregate":    16 * 1024 * 1024,
+}
+
+var (
+	ErrInvalidChunkSize    = errors.New("Invalid chunk size")
+	ErrChunkSizeExceeded   = errors.New("Chunk size exceeds limit")
+	ErrMessageTooLarge     = errors.New("Message length exceeds limit")
+	ErrTooManyChunkStreams = errors.New("Number of chunk streams exceeds limit")
+	ErrBufferExceeded      = errors.New("Buffered bytes exceed limit")
+)
+
+// limit returns configured limit, or def if not configured.
+func limit(configured int, def int) int {
+	if configured > 0 {
+		return configured
+	}
+
+	return def
+}
+
+// messageTypeName returns the name of message type used by message size limits.
+func messageTypeName(typeID uint32) string {
+	switch typeID {
+	case typeIDSetChunkSize, typeIDAbortMsg, typeIDAck, typeIDWindowAckSize,
+		typeIDSetPeerBandwidth, typeIDusrCtrlMsg:
+		return "control"
+	case typeIDCmdMsgAMF0, typeIDCmdMsgAMF3:
+		return "command"
+	case typeIDDataMsgAMF0, typeIDDataMsgAMF3:
+		return "data"
+	case typeISSharedObjectMsgAMF0, typeIDSharedObjectMsgAMF3:
+		return "sharedObject"
+	case typeIDAudioMsg:
+		return "audio"
+	case typeIDVideoMsg:
+		return "video"
+	case typeIDAggregateMsg:
+		return "aggregate"
+	default:
+		return ""
+	}
+}
+
+// maxMessageSize returns the maximum message length of message type.
+func (s *Server) maxMessageSize(typeID uint32) int {
+	name := messageTypeName(typeID)
+
+	if size, ok := s.config.MaxMessageSizes[name]; ok && size > 0 {
+		return size
+	}
+
+	if size, ok := defaultMaxMessageSizes[name]; ok {
+		return size
+	}
+
+	// Unknown message types
+	return defaultMaxMessageSizes["control"]
+}
+
+// checkChunkSize validates chunk size set by client.
+func (s *Server) checkChunkSize(chunkSize uint32) error {
+	// The most significant bit of chunk size must be zero
+	if chunkSize == 0 || chunkSize > 0x7FFFFFFF {
+		return ErrInvalidChunkSize
+	}
+
+	if chunkSize > uint32(limit(s.config.MaxChunkSize, defaultMaxChunkSize)) {
+		return ErrChunkSizeExceeded
+	}
+
+	return nil
+}
+
+// checkMessage validates the length of message about to be read on chunk stream.
+// Partial messages on all chunk streams count towards buffered bytes of the connection,
+// media packets queued for broadcasting are capped by the broadcast queue instead.
+//
+// Chunk streams are counted over the lifetime of the connection, as the latest header
+// of each chunk stream is kept for the following type 1, 2 and 3 headers.
+func (cs *ChunkStream) checkMessage(chunk *Chunk) error {
+	server := cs.conn.server
+
+	if _, ok := cs.curRead[chunk.CSID]; !ok &&
+		len(cs.curRead) >= limit(server.config.MaxChunkStreams, defaultMaxChunkStreams) {
+		return ErrTooManyChunkStreams
+	}
+
+	if int(chunk.Length) > server.maxMessageSize(chunk.TypeID) {
+		return ErrMessageTooLarge
+	}
+
+	if cs.partialBytes+int64(chunk.Length) > int64(limit(server.config.MaxBufferedBytes, defaultMaxBufferedBytes)) {
+		return ErrBufferExceeded
+	}
+
+	return nil
+}
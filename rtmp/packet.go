@@ -6,10 +6,12 @@ const (
 )
 
 type Packet struct {
-    packetType  int
-    timestamp   uint32
-    streamID    uint32
-    data        []byte
+    packetType      int
+    timestamp       uint32
+    streamID        uint32
+    data            []byte
+    keyFrame        bool // Whether video packet is a random access point, derived from coded frames
+    sequenceHeader  bool // Whether packet carries decoder configuration, e.g. AVC/AAC sequence header
 }
 
 func NewPacket(packetType int, timestamp uint32, streamID uint32, data []byte) *Packet {
@@ -20,3 +22,25 @@ func NewPacket(packetType int, timestamp uint32, streamID uint32, data []byte) *
         data:       data,
     }
 }
+
+// IsKeyFrame reports whether packet is a video random access point.
+func (p *Packet) IsKeyFrame() bool {
+    return p.keyFrame
+}
+
+// startsPlay reports whether packet can be the first media sent to a viewer joining the stream,
+// viewers start from a video key frame unless the stream is detected to carry audio only.
+func (p *Packet) startsPlay(tracks Tracks) bool {
+    if p.sequenceHeader {
+        return false
+    }
+
+    switch p.packetType {
+    case typeVideo:
+        return p.keyFrame
+    case typeAudio:
+        return tracks.AudioOnly()
+    }
+
+    return false
+}
@@ -0,0 +1,374 @@
+package rtmp
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"io"
+	"io/ioutil"
+	"net"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	log "github.com/sirupsen/logrus"
+)
+
+const (
+	rtmptContentType     = "application/x-fcs"
+	rtmptMaxInterval     = 0x21             // Longest polling interval hint sent to client
+	rtmptMaxPending      = 4 * 1024 * 1024  // Max bytes waiting to be polled by or read from client in each direction
+	rtmptSessionTimeout  = 30 * time.Second // Sessions not polled within the timeout are closed
+	rtmptSessionIDLength = 8
+)
+
+var ErrRTMPTPendingExceeded = errors.New("Bytes sent by RTMPT client but not read exceed limit")
+
+// rtmptTimeoutError is returned when a deadline of RTMPT session is exceeded.
+type rtmptTimeoutError struct{}
+
+func (rtmptTimeoutError) Error() string   { return "i/o timeout" }
+func (rtmptTimeoutError) Timeout() bool   { return true }
+func (rtmptTimeoutError) Temporary() bool { return true }
+
+// rtmptAddr is the address of HTTP client of RTMPT session.
+type rtmptAddr string
+
+func (addr rtmptAddr) Network() string { return "rtmpt" }
+func (addr rtmptAddr) String() string  { return string(addr) }
+
+// rtmptConn is a net.Conn over RTMPT session, which lets RTMPT sessions
+// be served as normal RTMP connections.
+// Bytes POSTed by client through /send are read by Read,
+// bytes written by Write are returned to client on the next /send or /idle.
+type rtmptConn struct {
+	lock          sync.Mutex
+	id            string
+	localAddr     net.Addr
+	remoteAddr    net.Addr
+	incoming      []byte // Bytes sent by client but not read yet
+	outgoing      []byte // Bytes written but not polled by client yet
+	interval      byte   // Polling interval hint sent to client
+	lastPoll      time.Time
+	readDeadline  time.Time
+	writeDeadline time.Time
+	readable      chan struct{} // Notified when incoming bytes arrive or read deadline changes
+	writable      chan struct{} // Notified when outgoing bytes are polled or write deadline changes
+	closed        chan struct{}
+	closeOnce     sync.Once
+	onClose       func()
+}
+
+func newRTMPTConn(id string, localAddr net.Addr, remoteAddr net.Addr, onClose func()) *rtmptConn {
+	return &rtmptConn{
+		id:         id,
+		localAddr:  localAddr,
+		remoteAddr: remoteAddr,
+		interval:   1,
+		lastPoll:   time.Now(),
+		readable:   make(chan struct{}, 1),
+		writable:   make(chan struct{}, 1),
+		closed:     make(chan struct{}),
+		onClose:    onClose,
+	}
+}
+
+func notify(ch chan struct{}) {
+	select {
+	case ch <- struct{}{}:
+	default:
+	}
+}
+
+// deadlineTimer returns a channel fired at deadline, or nil if there's no deadline.
+func deadlineTimer(deadline time.Time) (<-chan time.Time, func() bool) {
+	if deadline.IsZero() {
+		return nil, func() bool { return false }
+	}
+
+	timer := time.NewTimer(time.Until(deadline))
+	return timer.C, timer.Stop
+}
+
+func (c *rtmptConn) Read(b []byte) (int, error) {
+	for {
+		c.lock.Lock()
+		if len(c.incoming) > 0 {
+			n := copy(b, c.incoming)
+			c.incoming = c.incoming[n:]
+			c.lock.Unlock()
+			return n, nil
+		}
+		deadline := c.readDeadline
+		c.lock.Unlock()
+
+		if !deadline.IsZero() && !time.Now().Before(deadline) {
+			return 0, rtmptTimeoutError{}
+		}
+
+		timeout, stop := deadlineTimer(deadline)
+
+		select {
+		case <-c.readable:
+			stop()
+		case <-timeout:
+			return 0, rtmptTimeoutError{}
+		case <-c.closed:
+			stop()
+			return 0, io.EOF
+		}
+	}
+}
+
+func (c *rtmptConn) Write(b []byte) (int, error) {
+	for {
+		c.lock.Lock()
+		if len(c.outgoing) < rtmptMaxPending {
+			c.outgoing = append(c.outgoing, b...)
+			c.lock.Unlock()
+			return len(b), nil
+		}
+		deadline := c.writeDeadline
+		c.lock.Unlock()
+
+		if !deadline.IsZero() && !time.Now().Before(deadline) {
+			return 0, rtmptTimeoutError{}
+		}
+
+		timeout, stop := deadlineTimer(deadline)
+
+		select {
+		case <-c.writable:
+			stop()
+		case <-timeout:
+			return 0, rtmptTimeoutError{}
+		case <-c.closed:
+			stop()
+			return 0, io.ErrClosedPipe
+		}
+	}
+}
+
+func (c *rtmptConn) Close() error {
+	c.closeOnce.Do(func() {
+		close(c.closed)
+		c.onClose()
+	})
+
+	return nil
+}
+
+func (c *rtmptConn) LocalAddr() net.Addr {
+	return c.localAddr
+}
+
+func (c *rtmptConn) RemoteAddr() net.Addr {
+	return c.remoteAddr
+}
+
+func (c *rtmptConn) SetDeadline(t time.Time) error {
+	c.SetReadDeadline(t)
+	return c.SetWriteDeadline(t)
+}
+
+func (c *rtmptConn) SetReadDeadline(t time.Time) error {
+	c.lock.Lock()
+	c.readDeadline = t
+	c.lock.Unlock()
+
+	notify(c.readable)
+	return nil
+}
+
+func (c *rtmptConn) SetWriteDeadline(t time.Time) error {
+	c.lock.Lock()
+	c.writeDeadline = t
+	c.lock.Unlock()
+
+	notify(c.writable)
+	return nil
+}
+
+// send appends bytes POSTed by client to incoming bytes,
+// client sending faster than the bytes are read is rejected like Write limits outgoing bytes.
+func (c *rtmptConn) send(b []byte) error {
+	c.lock.Lock()
+	if len(c.incoming)+len(b) > rtmptMaxPending {
+		c.lock.Unlock()
+		return ErrRTMPTPendingExceeded
+	}
+
+	c.incoming = append(c.incoming, b...)
+	c.lock.Unlock()
+
+	notify(c.readable)
+	return nil
+}
+
+// poll returns the polling interval hint followed by outgoing bytes.
+func (c *rtmptConn) poll() []byte {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+
+	c.lastPoll = time.Now()
+
+	if len(c.outgoing) > 0 {
+		c.interval = 1
+	} else if c.interval < rtmptMaxInterval {
+		c.interval++
+	}
+
+	resp := append([]byte{c.interval}, c.outgoing...)
+	c.outgoing = nil
+
+	notify(c.writable)
+	return resp
+}
+
+func (c *rtmptConn) expired(now time.Time) bool {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+
+	return now.Sub(c.lastPoll) > rtmptSessionTimeout
+}
+
+// RTMPTHandler serves RTMP sessions tunneled over HTTP (RTMPT).
+// Each session is served by the RTMP server as a normal RTMP connection.
+type RTMPTHandler struct {
+	server   *Server
+	lock     sync.Mutex
+	sessions map[string]*rtmptConn // Map<Session ID>*rtmptConn
+}
+
+func NewRTMPTHandler(server *Server) *RTMPTHandler {
+	h := &RTMPTHandler{
+		server:   server,
+		sessions: make(map[string]*rtmptConn),
+	}
+
+	go h.closeExpiredSessions()
+
+	return h
+}
+
+func (h *RTMPTHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	// Path: /<command>/<session ID>/<sequence number>, or /open/1
+	parts := strings.Split(strings.Trim(r.URL.Path, "/"), "/")
+
+	switch parts[0] {
+	case "open":
+		h.open(w, r)
+	case "send", "idle", "close":
+		if len(parts) < 2 {
+			http.NotFound(w, r)
+			return
+		}
+
+		h.lock.Lock()
+		conn, ok := h.sessions[parts[1]]
+		h.lock.Unlock()
+
+		if !ok {
+			http.NotFound(w, r)
+			return
+		}
+
+		switch parts[0] {
+		case "send":
+			body, err := ioutil.ReadAll(io.LimitReader(r.Body, rtmptMaxPending))
+			if err != nil {
+				http.Error(w, "Bad request", http.StatusBadRequest)
+				return
+			}
+
+			err = conn.send(body)
+			if err != nil {
+				log.WithFields(log.Fields{
+					"session": parts[1],
+					"ip":      r.RemoteAddr,
+					"err":     err,
+				}).Warning("Closing RTMPT session.")
+
+				conn.Close()
+				http.Error(w, "Request entity too large", http.StatusRequestEntityTooLarge)
+				return
+			}
+
+			h.respond(w, conn.poll())
+		case "idle":
+			h.respond(w, conn.poll())
+		case "close":
+			conn.Close()
+			h.respond(w, []byte{0})
+		}
+	default:
+		// e.g. /fcs/ident2 probed by Flash Player
+		http.NotFound(w, r)
+	}
+}
+
+func (h *RTMPTHandler) open(w http.ResponseWriter, r *http.Request) {
+	buf := make([]byte, rtmptSessionIDLength)
+	_, err := rand.Read(buf)
+	if err != nil {
+		http.Error(w, "Internal server error", http.StatusInternalServerError)
+		return
+	}
+
+	id := hex.EncodeToString(buf)
+
+	localAddr := rtmptAddr(r.Host)
+	if addr, ok := r.Context().Value(http.LocalAddrContextKey).(net.Addr); ok {
+		localAddr = rtmptAddr(addr.String())
+	}
+
+	conn := newRTMPTConn(id, localAddr, rtmptAddr(r.RemoteAddr), func() {
+		h.lock.Lock()
+		delete(h.sessions, id)
+		h.lock.Unlock()
+	})
+
+	h.lock.Lock()
+	h.sessions[id] = conn
+	h.lock.Unlock()
+
+	log.WithFields(log.Fields{
+		"session": id,
+		"ip":      r.RemoteAddr,
+	}).Info("RTMPT session opened.")
+
+	go h.server.HandleRTMPRequest(conn)
+
+	h.respond(w, []byte(id+"\n"))
+}
+
+func (h *RTMPTHandler) respond(w http.ResponseWriter, body []byte) {
+	w.Header().Set("Content-Type", rtmptContentType)
+	w.Header().Set("Cache-Control", "no-cache")
+	w.Write(body)
+}
+
+// closeExpiredSessions closes sessions which client has stopped polling.
+func (h *RTMPTHandler) closeExpiredSessions() {
+	for now := range time.Tick(rtmptSessionTimeout / 2) {
+		h.lock.Lock()
+		expired := make([]*rtmptConn, 0)
+		for _, conn := range h.sessions {
+			if conn.expired(now) {
+				expired = append(expired, conn)
+			}
+		}
+		h.lock.Unlock()
+
+		for _, conn := range expired {
+			log.WithField("session", conn.id).Info("RTMPT session expired.")
+			conn.Close()
+		}
+	}
+}
@@ -0,0 +1,239 @@
+package rtmp
+
+import (
+	"bytes"
+	"io"
+	"io/ioutil"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+)
+
+func newTestRTMPTConn() *rtmptConn {
+	return newRTMPTConn("session", rtmptAddr("127.0.0.1:80"), rtmptAddr("127.0.0.1:12345"), func() {})
+}
+
+func TestRTMPTConnRead(t *testing.T) {
+	conn := newTestRTMPTConn()
+
+	// Read waits until client sends bytes
+	go func() {
+		time.Sleep(20 * time.Millisecond)
+		conn.send([]byte("hello"))
+	}()
+
+	buf := make([]byte, 3)
+	if n, err := conn.Read(buf); err != nil || string(buf[:n]) != "hel" {
+		t.Errorf("Read() = %q, %v, want \"hel\"", buf[:n], err)
+	}
+
+	if n, err := conn.Read(buf); err != nil || string(buf[:n]) != "lo" {
+		t.Errorf("Read() = %q, %v, want \"lo\"", buf[:n], err)
+	}
+
+	// Read times out at read deadline
+	conn.SetReadDeadline(time.Now().Add(20 * time.Millisecond))
+
+	_, err := conn.Read(buf)
+	if netErr, ok := err.(net.Error); !ok || !netErr.Timeout() {
+		t.Errorf("Read() = %v, want timeout error", err)
+	}
+
+	// Past deadline times out immediately
+	if _, err := conn.Read(buf); err == nil {
+		t.Error("Read() after deadline succeeded")
+	}
+
+	// Deadline extended while reading
+	conn.SetReadDeadline(time.Time{})
+
+	done := make(chan error, 1)
+	go func() {
+		_, err := conn.Read(buf)
+		done <- err
+	}()
+
+	time.Sleep(20 * time.Millisecond)
+	conn.SetReadDeadline(time.Now().Add(10 * time.Millisecond))
+
+	select {
+	case err := <-done:
+		if err == nil {
+			t.Error("Read() after deadline set succeeded")
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("Read() blocked after deadline set")
+	}
+
+	// Read returns EOF once closed
+	conn.SetReadDeadline(time.Time{})
+	conn.Close()
+
+	if _, err := conn.Read(buf); err != io.EOF {
+		t.Errorf("Read() of closed conn = %v, want %v", err, io.EOF)
+	}
+}
+
+func TestRTMPTConnWrite(t *testing.T) {
+	conn := newTestRTMPTConn()
+
+	if _, err := conn.Write([]byte("hello")); err != nil {
+		t.Fatalf("Write() = %v", err)
+	}
+
+	// Outgoing bytes are polled after the interval hint
+	if resp := conn.poll(); resp[0] != 1 || string(resp[1:]) != "hello" {
+		t.Errorf("poll() = %q, want interval 1 followed by \"hello\"", resp)
+	}
+
+	// Interval hint increases while there are no outgoing bytes
+	if resp := conn.poll(); len(resp) != 1 || resp[0] != 2 {
+		t.Errorf("poll() = %q, want interval 2", resp)
+	}
+
+	// Write blocks once pending bytes exceed the limit, until timeout or polled
+	if _, err := conn.Write(make([]byte, rtmptMaxPending)); err != nil {
+		t.Fatalf("Write() = %v", err)
+	}
+
+	conn.SetWriteDeadline(time.Now().Add(20 * time.Millisecond))
+
+	_, err := conn.Write([]byte("blocked"))
+	if netErr, ok := err.(net.Error); !ok || !netErr.Timeout() {
+		t.Errorf("Write() = %v, want timeout error", err)
+	}
+
+	conn.SetWriteDeadline(time.Time{})
+
+	done := make(chan error, 1)
+	go func() {
+		_, err := conn.Write([]byte("unblocked"))
+		done <- err
+	}()
+
+	time.Sleep(20 * time.Millisecond)
+	conn.poll()
+
+	select {
+	case err := <-done:
+		if err != nil {
+			t.Errorf("Write() after polled = %v", err)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("Write() blocked after polled")
+	}
+
+	if resp := conn.poll(); string(resp[1:]) != "unblocked" {
+		t.Errorf("poll() = %q, want \"unblocked\"", resp[1:])
+	}
+
+	// Client sending more than the limit is rejected
+	if err := conn.send(make([]byte, rtmptMaxPending+1)); err != ErrRTMPTPendingExceeded {
+		t.Errorf("send() = %v, want %v", err, ErrRTMPTPendingExceeded)
+	}
+
+	conn.Close()
+
+	if _, err := conn.Write(make([]byte, rtmptMaxPending)); err != nil {
+		t.Errorf("Write() within limit of closed conn = %v", err)
+	}
+
+	if _, err := conn.Write([]byte("closed")); err != io.ErrClosedPipe {
+		t.Errorf("Write() of closed conn = %v, want %v", err, io.ErrClosedPipe)
+	}
+}
+
+// postRTMPT POSTs body to path of RTMPT server, returns status code and response body.
+func postRTMPT(t *testing.T, server *httptest.Server, path string, body []byte) (int, []byte) {
+	resp, err := http.Post(server.URL+path, rtmptContentType, bytes.NewReader(body))
+	if err != nil {
+		t.Fatalf("POST %s = %v", path, err)
+	}
+	defer resp.Body.Close()
+
+	respBody, err := ioutil.ReadAll(resp.Body)
+	if err != nil {
+		t.Fatalf("Reading response of %s = %v", path, err)
+	}
+
+	if resp.StatusCode == http.StatusOK && resp.Header.Get("Content-Type") != rtmptContentType {
+		t.Errorf("Content-Type of %s = %s", path, resp.Header.Get("Content-Type"))
+	}
+
+	return resp.StatusCode, respBody
+}
+
+func TestRTMPTSession(t *testing.T) {
+	rtmpServer, err := NewRTMPServer(&Config{})
+	if err != nil {
+		t.Fatalf("NewRTMPServer() = %v", err)
+	}
+
+	handler := NewRTMPTHandler(rtmpServer)
+	server := httptest.NewServer(handler)
+	defer server.Close()
+
+	if status, _ := postRTMPT(t, server, "/fcs/ident2", nil); status != http.StatusNotFound {
+		t.Errorf("/fcs/ident2 status = %d, want %d", status, http.StatusNotFound)
+	}
+
+	status, body := postRTMPT(t, server, "/open/1", nil)
+	if status != http.StatusOK {
+		t.Fatalf("/open status = %d", status)
+	}
+
+	id := strings.TrimSpace(string(body))
+	if len(id) != 2*rtmptSessionIDLength {
+		t.Fatalf("/open session ID = %q", id)
+	}
+
+	// C0 and C1 of simple handshake
+	C0C1 := make([]byte, c0Len+c1Len)
+	C0C1[0] = 3
+
+	status, body = postRTMPT(t, server, "/send/"+id+"/1", C0C1)
+	if status != http.StatusOK || len(body) == 0 {
+		t.Fatalf("/send status = %d, %d bytes", status, len(body))
+	}
+
+	// S0, S1 and S2 are returned on polls after the interval hint
+	received := body[1:]
+
+	for seq := 2; len(received) < s0Len+s1Len+s2Len && seq < 100; seq++ {
+		time.Sleep(10 * time.Millisecond)
+
+		status, body = postRTMPT(t, server, "/idle/"+id+"/"+strconv.Itoa(seq), nil)
+		if status != http.StatusOK || len(body) == 0 {
+			t.Fatalf("/idle status = %d, %d bytes", status, len(body))
+		}
+
+		received = append(received, body[1:]...)
+	}
+
+	if len(received) != s0Len+s1Len+s2Len || received[0] != 3 {
+		t.Fatalf("Received %d bytes of S0S1S2, want %d", len(received), s0Len+s1Len+s2Len)
+	}
+
+	// S2 echoes C1 in simple handshake
+	if !bytes.Equal(received[s0Len+s1Len:], C0C1[c0Len:]) {
+		t.Error("S2 does not echo C1")
+	}
+
+	status, body = postRTMPT(t, server, "/close/"+id+"/1", nil)
+	if status != http.StatusOK || !bytes.Equal(body, []byte{0}) {
+		t.Errorf("/close = %d, % x", status, body)
+	}
+
+	// Closed session is removed
+	if status, _ := postRTMPT(t, server, "/idle/"+id+"/1", nil); status != http.StatusNotFound {
+		t.Errorf("/idle of closed session status = %d, want %d", status, http.StatusNotFound)
+	}
+
+	if status, _ := postRTMPT(t, server, "/send/unknown/1", []byte{0}); status != http.StatusNotFound {
+		t.Errorf("/send of unknown session status = %d, want %d", status, http.StatusNotFound)
+	}
+}
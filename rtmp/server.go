@@ -2,50 +2,143 @@ package rtmp
 
 import (
 	"net"
-	"sync"
 
 	log "github.com/sirupsen/logrus"
 )
 
 type Server struct {
-	newStreamer chan *Conn
-	newViewer   chan *Conn
-	channels    sync.Map // Map<Stream Name>*Channel
+	config       *Config
+	newStreamer  chan *Conn
+	newViewer    chan *Conn
+	vhosts       map[string]*VHost // Map<Host Name>*VHost
+	defaultVHost *VHost
+	acl          *ACL // Rules of IPs allowed to connect
+	limiter      *connLimiter
 }
 
-func NewRTMPServer() *Server {
-	return &Server{
+func NewRTMPServer(config *Config) (*Server, error) {
+	s := &Server{
+		config:      config,
 		newStreamer: make(chan *Conn),
 		newViewer:   make(chan *Conn),
+		vhosts:      make(map[string]*VHost),
+		limiter:     newConnLimiter(config.MaxConnections, config.MaxConnectionsPerIP),
 	}
+
+	acl, err := NewACL(config.Allow, config.Deny)
+	if err != nil {
+		return nil, err
+	}
+
+	s.acl = acl
+
+	defaultVHost, err := NewVHost(config.DefaultVHostConfig())
+	if err != nil {
+		return nil, err
+	}
+
+	s.defaultVHost = defaultVHost
+
+	for _, vhostConfig := range config.VHosts {
+		if vhostConfig.Name == DefaultVHost {
+			continue
+		}
+
+		vhost, err := NewVHost(vhostConfig)
+		if err != nil {
+			return nil, err
+		}
+
+		s.vhosts[vhostConfig.Name] = vhost
+
+		for _, alias := range vhostConfig.Aliases {
+			s.vhosts[alias] = vhost
+		}
+	}
+
+	return s, nil
+}
+
+// VHost returns the virtual host of name, or the default virtual host if name is DefaultVHost.
+func (s *Server) VHost(name string) *VHost {
+	if name == DefaultVHost {
+		return s.defaultVHost
+	}
+
+	return s.vhosts[name]
+}
+
+// resolveVHost returns the virtual host requested by client,
+// or the default virtual host if no virtual host matches.
+func (s *Server) resolveVHost(tcURL string, app string) *VHost {
+	name := parseVHostName(tcURL, app)
+
+	if vhost, ok := s.vhosts[name]; ok {
+		return vhost
+	}
+
+	return s.defaultVHost
 }
 
 func (s *Server) HandleRTMPRequest(netConn net.Conn) {
-	conn := NewConn(netConn, s.newStreamer, s.newViewer)
+	ip := remoteIP(netConn.RemoteAddr())
+
+	if !s.acl.Permit(ip) {
+		log.WithField("ip", ip).Warning("Connection denied by ACL.")
+		netConn.Close()
+		return
+	}
+
+	if !s.limiter.acquire(ip) {
+		log.WithField("ip", ip).Warning("Too many connections, connection refused.")
+		netConn.Close()
+		return
+	}
+	defer s.limiter.release(ip)
+
+	conn := NewConn(netConn, s)
 	defer func() {
-		if conn.info != nil {
+		// Connections rejected before joining a channel have no channel to leave
+		if conn.info != nil && conn.channel != nil {
+			vhost := conn.vhost
+			app := conn.app
 			streamName := conn.info.Name
 			isPublisher := conn.isPublisher
+			key := channelKey(app, streamName)
 
-			if ch, ok := s.channels.Load(streamName); ok {
+			if ch, ok := vhost.channels.Load(key); ok {
 				// Remove connection from channel list
 				channel := ch.(*Channel)
 
 				if isPublisher {
+					// Streamer kicked off by a new streamer no longer owns the channel
 					channel.lock.Lock()
-					channel.streamer = nil
+					owned := channel.streamer == conn
+					if owned {
+						channel.streamer = nil
+					}
 					channel.lock.Unlock()
+
+					if owned {
+						vhost.hooksOf(app).Notify(conn.hookEvent(HookOnUnpublish))
+					}
 				} else {
 					if result := channel.removeViewer(conn); !result {
 						log.Warn("Cannot find connection in channel list.")
 					}
+
+					vhost.hooksOf(app).Notify(conn.hookEvent(HookOnStop))
 				}
 
 				// Remove channel if there're no streamer and viewers
 				channel.lock.RLock()
 				if channel.streamer == nil && len(channel.viewers) == 0 {
-					log.WithField("streamName", streamName).Info("Channel removed.")
-					s.channels.Delete(conn.info.Name)
+					log.WithFields(log.Fields{
+						"vhost":      vhost.Name(),
+						"app":        app,
+						"streamName": streamName,
+					}).Info("Channel removed.")
+					vhost.channels.Delete(key)
 				}
 				channel.lock.RUnlock()
 			}
@@ -68,22 +161,30 @@ func (s *Server) Monitor() {
 	for {
 		select {
 		case conn := <-s.newStreamer:
+			vhost := conn.vhost
+			app := conn.app
 			streamName := conn.info.Name
-
-			if ch, ok := s.channels.Load(streamName); !ok {
+			key := channelKey(app, streamName)
+			logger := log.WithFields(log.Fields{
+				"vhost":      vhost.Name(),
+				"app":        app,
+				"streamName": streamName,
+			})
+
+			if ch, ok := vhost.channels.Load(key); !ok {
 				// Channel not exists, create a new channel
-				newChannel := NewChannel(streamName)
+				newChannel := NewChannel(app, streamName)
 				newChannel.streamer = conn
-				s.channels.Store(conn.info.Name, newChannel)
+				newChannel.timestamps.reset(conn)
+				vhost.channels.Store(key, newChannel)
 				conn.channel = newChannel
-				log.WithField("streamName", streamName).Info("New streamer connected.")
+				logger.Info("New streamer connected.")
 			} else {
 				channel := ch.(*Channel)
 
 				if channel.streamer != nil {
 					// Channel already existed, kick off the existed streamer and replace with new streamer
-					log.WithField("streamName",
-						streamName).Info("Duplicate streamer detected, disconnect existing streamer.")
+					logger.Info("Duplicate streamer detected, disconnect existing streamer.")
 					channel.streamer.Close()
 
 					channel.lock.Lock()
@@ -91,7 +192,10 @@ func (s *Server) Monitor() {
 					conn.channel = channel
 					channel.lock.Unlock()
 
-					log.WithField("streamName", streamName).Info("New streamer connected.")
+					// Continue timeline of the channel from the new streamer
+					channel.timestamps.reset(conn)
+
+					logger.Info("New streamer connected.")
 				} else {
 					// Channel already existed, which was created by pending viewers
 					channel.lock.Lock()
@@ -99,22 +203,33 @@ func (s *Server) Monitor() {
 					conn.channel = channel
 					channel.lock.Unlock()
 
-					log.WithField("streamName", streamName).Info("New streamer connected.")
+					// Continue timeline of the channel from the new streamer
+					channel.timestamps.reset(conn)
+
+					logger.Info("New streamer connected.")
 				}
 			}
 
 			conn.channelCreated <- true
 		case conn := <-s.newViewer:
+			vhost := conn.vhost
+			app := conn.app
 			streamName := conn.info.Name
-
-			if ch, ok := s.channels.Load(streamName); !ok {
+			key := channelKey(app, streamName)
+			logger := log.WithFields(log.Fields{
+				"vhost":      vhost.Name(),
+				"app":        app,
+				"streamName": streamName,
+			})
+
+			if ch, ok := vhost.channels.Load(key); !ok {
 				// Channel not exists, create a new channel
-				newChannel := NewChannel(streamName)
+				newChannel := NewChannel(app, streamName)
 				newChannel.viewers = append(newChannel.viewers, conn)
-				s.channels.Store(streamName, newChannel)
+				vhost.channels.Store(key, newChannel)
 				conn.channel = newChannel
 
-				log.WithField("streamName", streamName).Info("New player connected.")
+				logger.Info("New player connected.")
 			} else {
 				// Channel already existed, add connection to viewers list
 				channel := ch.(*Channel)
@@ -123,7 +238,7 @@ func (s *Server) Monitor() {
 				channel.viewers = append(channel.viewers, conn)
 				channel.lock.Unlock()
 
-				log.WithField("streamName", streamName).Info("New player connected.")
+				logger.Info("New player connected.")
 			}
 
 			conn.channelCreated <- true
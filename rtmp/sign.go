@@ -0,0 +1,86 @@
+package rtmp
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"net"
+	"net/url"
+	"strconv"
+	"time"
+)
+
+var (
+	ErrSignatureMissing = errors.New("Signature missing")
+	ErrSignatureInvalid = errors.New("Signature invalid")
+	ErrSignatureExpired = errors.New("Signature expired")
+)
+
+// Actions of signed URLs, a signature is only valid for the action it was signed for.
+const (
+	SignActionPlay    = "play"
+	SignActionPublish = "publish"
+)
+
+// Sign returns the HMAC-SHA256 signature of action (play or publish) of stream under app,
+// expiring at expires (Unix time). The signature is bound to client's IP if ip is not empty.
+//
+// Signed URLs carry the expiry and signature as query string parameters,
+// e.g. "rtmp://host/live/mylive?expires=1700000000&sign=<signature>".
+func Sign(secret string, action string, app string, streamName string, expires int64, ip string) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(channelKey(app, streamName)))
+	mac.Write([]byte("?action=" + action))
+	mac.Write([]byte("&expires=" + strconv.FormatInt(expires, 10)))
+
+	if ip != "" {
+		mac.Write([]byte("&ip=" + ip))
+	}
+
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+// VerifySignedURL verifies the expiry and signature parameters of a signed URL
+// requested for action by client of ip at now, against the signing configuration of app.
+// Signed URLs are not required if app has no signing secret configured.
+func VerifySignedURL(config *AppConfig, action string, streamName string, params url.Values, ip string, now time.Time) error {
+	if config.SignSecret == "" {
+		return nil
+	}
+
+	sign := params.Get("sign")
+	if sign == "" {
+		return ErrSignatureMissing
+	}
+
+	expires, err := strconv.ParseInt(params.Get("expires"), 10, 64)
+	if err != nil {
+		return ErrSignatureInvalid
+	}
+
+	if !config.SignBindIP {
+		ip = ""
+	}
+
+	expected := Sign(config.SignSecret, action, config.Name, streamName, expires, ip)
+	if !hmac.Equal([]byte(expected), []byte(sign)) {
+		return ErrSignatureInvalid
+	}
+
+	if now.After(time.Unix(expires, 0).Add(config.SignSkew.Duration)) {
+		return ErrSignatureExpired
+	}
+
+	return nil
+}
+
+// remoteIP returns the IP part of a remote address.
+func remoteIP(addr net.Addr) string {
+	host, _, err := net.SplitHostPort(addr.String())
+	if err != nil {
+		return addr.String()
+	}
+
+	return host
+}
@@ -0,0 +1,73 @@
+package rtmp
+
+import (
+	"net/url"
+	"strconv"
+	"testing"
+	"time"
+)
+
+func signedParams(sign string, expires int64) url.Values {
+	params := url.Values{}
+	params.Set("expires", strconv.FormatInt(expires, 10))
+	params.Set("sign", sign)
+
+	return params
+}
+
+func TestVerifySignedURL(t *testing.T) {
+	config := &AppConfig{Name: "live", SignSecret: "s3cr3t", SignSkew: Duration{30 * time.Second}}
+	now := time.Unix(1700000000, 0)
+	expires := now.Add(time.Minute).Unix()
+
+	play := Sign(config.SignSecret, SignActionPlay, "live", "mylive", expires, "")
+	publish := Sign(config.SignSecret, SignActionPublish, "live", "mylive", expires, "")
+
+	tests := []struct {
+		name   string
+		action string
+		stream string
+		params url.Values
+		now    time.Time
+		err    error
+	}{
+		{"play", SignActionPlay, "mylive", signedParams(play, expires), now, nil},
+		{"publish", SignActionPublish, "mylive", signedParams(publish, expires), now, nil},
+		{"play signature on publish", SignActionPublish, "mylive", signedParams(play, expires), now, ErrSignatureInvalid},
+		{"publish signature on play", SignActionPlay, "mylive", signedParams(publish, expires), now, ErrSignatureInvalid},
+		{"other stream", SignActionPlay, "other", signedParams(play, expires), now, ErrSignatureInvalid},
+		{"altered expiry", SignActionPlay, "mylive", signedParams(play, expires+60), now, ErrSignatureInvalid},
+		{"missing signature", SignActionPlay, "mylive", url.Values{}, now, ErrSignatureMissing},
+		{"invalid expiry", SignActionPlay, "mylive", url.Values{"sign": {play}}, now, ErrSignatureInvalid},
+		{"within skew", SignActionPlay, "mylive", signedParams(play, expires), now.Add(80 * time.Second), nil},
+		{"expired", SignActionPlay, "mylive", signedParams(play, expires), now.Add(100 * time.Second), ErrSignatureExpired},
+	}
+
+	for _, test := range tests {
+		err := VerifySignedURL(config, test.action, test.stream, test.params, "127.0.0.1", test.now)
+		if err != test.err {
+			t.Errorf("%s: VerifySignedURL() = %v, want %v", test.name, err, test.err)
+		}
+	}
+}
+
+func TestVerifySignedURLBindIP(t *testing.T) {
+	config := &AppConfig{Name: "live", SignSecret: "s3cr3t", SignBindIP: true}
+	now := time.Unix(1700000000, 0)
+	expires := now.Add(time.Minute).Unix()
+
+	params := signedParams(Sign(config.SignSecret, SignActionPlay, "live", "mylive", expires, "10.0.0.1"), expires)
+
+	if err := VerifySignedURL(config, SignActionPlay, "mylive", params, "10.0.0.1", now); err != nil {
+		t.Errorf("VerifySignedURL() from bound IP = %v", err)
+	}
+
+	if err := VerifySignedURL(config, SignActionPlay, "mylive", params, "10.0.0.2", now); err != ErrSignatureInvalid {
+		t.Errorf("VerifySignedURL() from other IP = %v, want %v", err, ErrSignatureInvalid)
+	}
+
+	// Signed URLs are not required without signing secret
+	if err := VerifySignedURL(&AppConfig{Name: "live"}, SignActionPublish, "mylive", url.Values{}, "10.0.0.1", now); err != nil {
+		t.Errorf("VerifySignedURL() without secret = %v", err)
+	}
+}
@@ -0,0 +1,127 @@
+package rtmp
+
+import (
+	"sync"
+)
+
+const (
+	// Timestamps jumping forward or backward by more than maxTimestampJump milliseconds
+	// on a track are treated as discontinuities
+	maxTimestampJump = 10000
+
+	// Step in milliseconds after a discontinuity if frame interval of the track is unknown
+	defaultTimestampStep = 20
+)
+
+// timestampNormalizer normalizes publisher timestamps of a channel into a continuous timeline
+// starting from zero, which survives 32-bit rollover, publisher reconnections and
+// encoder timestamp jumps. Timestamps are compared with wrapping arithmetic,
+// thus the timeline keeps going across rollover of 32-bit timestamps.
+type timestampNormalizer struct {
+	lock        sync.Mutex
+	streamer    *Conn // Streamer whose timestamps are normalized
+	started     bool
+	rebase      bool      // Whether the next timestamp starts a new segment, e.g. publisher reconnected
+	offset      uint32    // Added to publisher timestamps to get normalized timestamps
+	startOffset [2]uint32 // Added to timestamps of each track which started behind the other track
+	hasStart    [2]bool   // Whether start offset of each track has been computed in the segment
+	latest      uint32    // Latest normalized timestamp of all tracks
+	lastOut     [2]uint32 // Latest normalized timestamp of each track, indexed by packet type
+	lastDelta   [2]uint32 // Latest frame interval of each track
+	hasLast     [2]bool
+}
+
+func newTimestampNormalizer() *timestampNormalizer {
+	return &timestampNormalizer{}
+}
+
+// reset hands the channel over to streamer, whose next timestamp continues right after
+// the latest normalized timestamp regardless of its value.
+func (n *timestampNormalizer) reset(streamer *Conn) {
+	n.lock.Lock()
+	defer n.lock.Unlock()
+
+	n.streamer = streamer
+	n.rebase = true
+	n.startOffset = [2]uint32{}
+	n.hasStart = [2]bool{}
+}
+
+// normalize converts publisher timestamp of a packet type into normalized timestamp,
+// which never goes backward on each track.
+// Timestamps of a streamer which no longer owns the channel, e.g. kicked off by a new streamer,
+// are held at the latest timestamp and leave the timeline untouched.
+func (n *timestampNormalizer) normalize(streamer *Conn, packetType int, timestamp uint32) uint32 {
+	n.lock.Lock()
+	defer n.lock.Unlock()
+
+	if streamer != n.streamer {
+		return n.latest
+	}
+
+	if !n.started {
+		n.started = true
+		n.rebase = false
+		n.offset = -timestamp
+	}
+
+	if !n.hasStart[packetType] && !n.rebase {
+		// First packet of the track in the segment, a track starting behind or far ahead of
+		// the other track is shifted to start at the latest timestamp, keeping the interval of its packets
+		n.hasStart[packetType] = true
+
+		delta := int32(timestamp + n.offset - n.latest)
+		if delta < 0 || delta > maxTimestampJump {
+			n.startOffset[packetType] = uint32(-delta)
+		}
+	}
+
+	// Reference is the latest timestamp of the track, or of any track for the first packet of track
+	ref := n.latest
+	if n.hasLast[packetType] {
+		ref = n.lastOut[packetType]
+	}
+
+	out := timestamp + n.offset + n.startOffset[packetType]
+	delta := int32(out - ref)
+
+	if n.rebase || delta > maxTimestampJump || delta < -maxTimestampJump {
+		// Discontinuity, continue right after the latest timestamp of all tracks
+		// to keep audio and video interleaved
+		step := n.lastDelta[packetType]
+		if step == 0 {
+			step = defaultTimestampStep
+		}
+
+		out = n.latest + step
+		n.offset = out - timestamp - n.startOffset[packetType]
+		n.hasStart[packetType] = true
+		n.rebase = false
+	} else if delta < 0 {
+		// Small backward jitter of the track, hold the track still
+		out = ref
+	} else if n.hasLast[packetType] && delta > 0 {
+		n.lastDelta[packetType] = uint32(delta)
+	}
+
+	n.lastOut[packetType] = out
+	n.hasLast[packetType] = true
+
+	if int32(out-n.latest) > 0 {
+		n.latest = out
+	}
+
+	return out
+}
+
+// rebaseTimestamp rebases normalized timestamp on the timestamp of the first media written to player.
+// The other track may start slightly behind the first media, whose timestamps are clamped to zero
+// rather than wrapping around.
+func rebaseTimestamp(timestamp uint32, base uint32) uint32 {
+	delta := int32(timestamp - base)
+	if delta < 0 && delta >= -maxTimestampJump {
+		return 0
+	}
+
+	return timestamp - base
+}
@@ -0,0 +1,190 @@
+package rtmp
+
+import (
+	"testing"
+)
+
+type timestampInput struct {
+	packetType int
+	timestamp  uint32
+	want       uint32
+}
+
+func testNormalize(t *testing.T, n *timestampNormalizer, streamer *Conn, inputs []timestampInput) {
+	for i, input := range inputs {
+		if got := n.normalize(streamer, input.packetType, input.timestamp); got != input.want {
+			t.Errorf("#%d normalize(%d, %d) = %d, want %d", i, input.packetType, input.timestamp, got, input.want)
+		}
+	}
+}
+
+func TestNormalizeStartsFromZero(t *testing.T) {
+	testNormalize(t, newTimestampNormalizer(), nil, []timestampInput{
+		{typeVideo, 1000, 0},
+		{typeAudio, 1010, 10},
+		{typeVideo, 1040, 40},
+		{typeAudio, 1033, 33},
+		{typeVideo, 1080, 80},
+	})
+}
+
+func TestNormalizeJitter(t *testing.T) {
+	// Small backward jumps hold the track still
+	testNormalize(t, newTimestampNormalizer(), nil, []timestampInput{
+		{typeVideo, 0, 0},
+		{typeVideo, 40, 40},
+		{typeVideo, 30, 40},
+		{typeVideo, 80, 80},
+	})
+}
+
+func TestNormalizeJumps(t *testing.T) {
+	testNormalize(t, newTimestampNormalizer(), nil, []timestampInput{
+		{typeVideo, 0, 0},
+		{typeAudio, 0, 0},
+		{typeVideo, 40, 40},
+		{typeAudio, 23, 23},
+		// Jump forward over 10 seconds continues with the latest frame interval of the track,
+		// and the other track follows with the same offset
+		{typeVideo, 50040, 80},
+		{typeAudio, 50023, 63},
+		{typeVideo, 50080, 120},
+		// Jump backward over 10 seconds
+		{typeAudio, 100, 160},
+		{typeVideo, 120, 180},
+		{typeAudio, 123, 183},
+		// Jump of exactly 10 seconds is kept
+		{typeVideo, 10120, 10180},
+	})
+}
+
+func TestNormalizeRollover(t *testing.T) {
+	testNormalize(t, newTimestampNormalizer(), nil, []timestampInput{
+		{typeVideo, 0xFFFFFFB0, 0},
+		{typeAudio, 0xFFFFFFC0, 16},
+		{typeVideo, 0xFFFFFFD8, 40},
+		{typeVideo, 0, 80},
+		{typeAudio, 0x10, 96},
+		{typeVideo, 0x28, 120},
+	})
+
+	// Normalized timestamps also wrap around rather than being treated as a jump
+	n := newTimestampNormalizer()
+	testNormalize(t, n, nil, []timestampInput{
+		{typeVideo, 0, 0},
+	})
+
+	n.offset = 0
+	n.latest = 0xFFFFFFF0
+	n.lastOut[typeVideo] = 0xFFFFFFF0
+
+	testNormalize(t, n, nil, []timestampInput{
+		{typeVideo, 0x18, 0x18},
+		{typeAudio, 0x22, 0x22},
+	})
+}
+
+func TestNormalizeTrackStart(t *testing.T) {
+	// Track starting behind the other track starts at the latest timestamp,
+	// and keeps the interval of its packets rather than being held still
+	testNormalize(t, newTimestampNormalizer(), nil, []timestampInput{
+		{typeVideo, 1000, 0},
+		{typeVideo, 1040, 40},
+		{typeAudio, 1000, 40},
+		{typeAudio, 1023, 63},
+		{typeVideo, 1080, 80},
+		{typeAudio, 1046, 86},
+	})
+
+	// Track starting far ahead of the other track starts at the latest timestamp as well,
+	// leaving the other track untouched
+	testNormalize(t, newTimestampNormalizer(), nil, []timestampInput{
+		{typeVideo, 0, 0},
+		{typeVideo, 40, 40},
+		{typeAudio, 60000, 40},
+		{typeVideo, 80, 80},
+		{typeAudio, 60023, 63},
+	})
+}
+
+func TestNormalizeReset(t *testing.T) {
+	first, second, third := &Conn{}, &Conn{}, &Conn{}
+	n := newTimestampNormalizer()
+	n.reset(first)
+
+	testNormalize(t, n, first, []timestampInput{
+		{typeVideo, 5000, 0},
+		{typeAudio, 5000, 0},
+		{typeVideo, 5040, 40},
+		{typeAudio, 5023, 23},
+	})
+
+	// Publisher reconnects with timestamps restarting from zero,
+	// which continue right after the latest timestamp
+	n.reset(second)
+
+	testNormalize(t, n, second, []timestampInput{
+		{typeVideo, 0, 80},
+		{typeAudio, 0, 80},
+		{typeVideo, 40, 120},
+	})
+
+	// Reconnect with timestamps close to the previous ones is rebased as well
+	n.reset(third)
+
+	testNormalize(t, n, third, []timestampInput{
+		{typeAudio, 5040, 177},
+		{typeVideo, 5040, 177},
+		{typeVideo, 5080, 217},
+	})
+}
+
+func TestNormalizeKickedStreamer(t *testing.T) {
+	kicked, streamer := &Conn{}, &Conn{}
+	n := newTimestampNormalizer()
+	n.reset(kicked)
+
+	testNormalize(t, n, kicked, []timestampInput{
+		{typeVideo, 0, 0},
+		{typeVideo, 40, 40},
+	})
+
+	n.reset(streamer)
+
+	// Packets still read from the kicked streamer neither consume the rebase nor move the timeline
+	testNormalize(t, n, kicked, []timestampInput{
+		{typeVideo, 80, 40},
+		{typeVideo, 30000, 40},
+	})
+
+	testNormalize(t, n, streamer, []timestampInput{
+		{typeVideo, 1000, 80},
+		{typeAudio, 990, 80},
+		{typeVideo, 1040, 120},
+		{typeAudio, 1013, 103},
+	})
+}
+
+func TestRebaseTimestamp(t *testing.T) {
+	tests := []struct {
+		timestamp uint32
+		base      uint32
+		want      uint32
+	}{
+		{1000, 1000, 0},
+		{1040, 1000, 40},
+		// Other track starting behind the first media
+		{990, 1000, 0},
+		{0xFFFFFFF0, 0x10, 0},
+		// Across rollover
+		{0x10, 0xFFFFFFF0, 0x20},
+		// Far behind is treated as rollover
+		{0, 20000, 0xFFFFFFFF - 19999},
+	}
+
+	for _, test := range tests {
+		if got := rebaseTimestamp(test.timestamp, test.base); got != test.want {
+			t.Errorf("rebaseTimestamp(%#x, %#x) = %#x, want %#x", test.timestamp, test.base, got, test.want)
+		}
+	}
+}
@@ -0,0 +1,170 @@
+package rtmp
+
+import (
+	"crypto/tls"
+	"errors"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	log "github.com/sirupsen/logrus"
+)
+
+// certFile is a certificate loaded from certificate and key files.
+type certFile struct {
+	certPath string
+	keyPath  string
+	modTime  time.Time // Latest modification time of certificate and key files
+	cert     *tls.Certificate
+}
+
+func loadCertFile(certPath string, keyPath string) (*certFile, error) {
+	modTime, err := certModTime(certPath, keyPath)
+	if err != nil {
+		return nil, err
+	}
+
+	cert, err := tls.LoadX509KeyPair(certPath, keyPath)
+	if err != nil {
+		return nil, err
+	}
+
+	return &certFile{
+		certPath: certPath,
+		keyPath:  keyPath,
+		modTime:  modTime,
+		cert:     &cert,
+	}, nil
+}
+
+func certModTime(certPath string, keyPath string) (time.Time, error) {
+	certInfo, err := os.Stat(certPath)
+	if err != nil {
+		return time.Time{}, err
+	}
+
+	keyInfo, err := os.Stat(keyPath)
+	if err != nil {
+		return time.Time{}, err
+	}
+
+	if keyInfo.ModTime().After(certInfo.ModTime()) {
+		return keyInfo.ModTime(), nil
+	}
+
+	return certInfo.ModTime(), nil
+}
+
+// CertStore selects certificates of RTMPS connections by SNI,
+// certificates are reloaded when their files change.
+type CertStore struct {
+	lock        sync.RWMutex
+	defaultCert *certFile            // Used if SNI matches no virtual host
+	certs       map[string]*certFile // Map<Server Name>*certFile
+}
+
+// NewCertStore loads the default certificate and certificates of virtual hosts.
+// Certificates of virtual hosts are selected by virtual host's name and aliases.
+func NewCertStore(config *Config, certPath string, keyPath string) (*CertStore, error) {
+	store := &CertStore{
+		certs: make(map[string]*certFile),
+	}
+
+	if certPath != "" {
+		cert, err := loadCertFile(certPath, keyPath)
+		if err != nil {
+			return nil, err
+		}
+
+		store.defaultCert = cert
+	}
+
+	for _, vhost := range config.VHosts {
+		if vhost.TLSCert == "" {
+			continue
+		}
+
+		cert, err := loadCertFile(vhost.TLSCert, vhost.TLSKey)
+		if err != nil {
+			return nil, err
+		}
+
+		for _, name := range append([]string{vhost.Name}, vhost.Aliases...) {
+			store.certs[strings.ToLower(name)] = cert
+		}
+	}
+
+	if store.defaultCert == nil && len(store.certs) == 0 {
+		return nil, errors.New("No TLS certificate configured")
+	}
+
+	return store, nil
+}
+
+// GetCertificate returns the certificate of the server name requested by client,
+// falls back to the default certificate.
+func (s *CertStore) GetCertificate(hello *tls.ClientHelloInfo) (*tls.Certificate, error) {
+	s.lock.RLock()
+	defer s.lock.RUnlock()
+
+	if cert, ok := s.certs[strings.ToLower(hello.ServerName)]; ok {
+		return cert.cert, nil
+	}
+
+	if s.defaultCert != nil {
+		return s.defaultCert.cert, nil
+	}
+
+	return nil, errors.New("No TLS certificate for server name: " + hello.ServerName)
+}
+
+func (s *CertStore) TLSConfig() *tls.Config {
+	return &tls.Config{
+		GetCertificate: s.GetCertificate,
+	}
+}
+
+// Watch checks certificate files every interval and reloads the changed certificates.
+// Certificates failed to reload are kept unchanged.
+func (s *CertStore) Watch(interval time.Duration) {
+	for range time.Tick(interval) {
+		s.reload()
+	}
+}
+
+// reload reloads the certificates whose files have changed since loaded.
+func (s *CertStore) reload() {
+	s.lock.RLock()
+	files := make(map[*certFile]bool)
+	if s.defaultCert != nil {
+		files[s.defaultCert] = true
+	}
+	for _, cert := range s.certs {
+		files[cert] = true
+	}
+	s.lock.RUnlock()
+
+	for file := range files {
+		modTime, err := certModTime(file.certPath, file.keyPath)
+		if err != nil || !modTime.After(file.modTime) {
+			continue
+		}
+
+		cert, err := tls.LoadX509KeyPair(file.certPath, file.keyPath)
+		if err != nil {
+			log.WithFields(log.Fields{
+				"cert": file.certPath,
+				"err":  err,
+			}).Error("Fail to reload TLS certificate.")
+			continue
+		}
+
+		s.lock.Lock()
+		file.cert = &cert
+		file.modTime = modTime
+		s.lock.Unlock()
+
+		log.WithField("cert", file.certPath).Info("TLS certificate reloaded.")
+	}
+}
@@ -0,0 +1,196 @@
+package rtmp
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"io/ioutil"
+	"math/big"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+// writeCertFiles writes a self-signed certificate of commonName and its key to dir,
+// with modification time of modTime, returns paths of the certificate and key files.
+func writeCertFiles(t *testing.T, dir string, name string, commonName string, modTime time.Time) (string, string) {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatalf("GenerateKey() = %v", err)
+	}
+
+	template := &x509.Certificate{
+		SerialNumber: big.NewInt(time.Now().UnixNano()),
+		Subject:      pkix.Name{CommonName: commonName},
+		DNSNames:     []string{commonName},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(time.Hour),
+	}
+
+	der, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+	if err != nil {
+		t.Fatalf("CreateCertificate() = %v", err)
+	}
+
+	keyDER, err := x509.MarshalECPrivateKey(key)
+	if err != nil {
+		t.Fatalf("MarshalECPrivateKey() = %v", err)
+	}
+
+	certPath := filepath.Join(dir, name+".crt")
+	keyPath := filepath.Join(dir, name+".key")
+
+	files := map[string]*pem.Block{
+		certPath: {Type: "CERTIFICATE", Bytes: der},
+		keyPath:  {Type: "EC PRIVATE KEY", Bytes: keyDER},
+	}
+
+	for path, block := range files {
+		if err := ioutil.WriteFile(path, pem.EncodeToMemory(block), 0600); err != nil {
+			t.Fatalf("WriteFile() = %v", err)
+		}
+
+		if err := os.Chtimes(path, modTime, modTime); err != nil {
+			t.Fatalf("Chtimes() = %v", err)
+		}
+	}
+
+	return certPath, keyPath
+}
+
+// commonNameOf returns the common name of certificate selected for serverName.
+func commonNameOf(t *testing.T, store *CertStore, serverName string) string {
+	cert, err := store.GetCertificate(&tls.ClientHelloInfo{ServerName: serverName})
+	if err != nil {
+		t.Fatalf("GetCertificate(%q) = %v", serverName, err)
+	}
+
+	leaf, err := x509.ParseCertificate(cert.Certificate[0])
+	if err != nil {
+		t.Fatalf("ParseCertificate() = %v", err)
+	}
+
+	return leaf.Subject.CommonName
+}
+
+func newCertDir(t *testing.T) string {
+	dir, err := ioutil.TempDir("", "certs")
+	if err != nil {
+		t.Fatalf("TempDir() = %v", err)
+	}
+
+	return dir
+}
+
+func TestCertStoreSNI(t *testing.T) {
+	dir := newCertDir(t)
+	defer os.RemoveAll(dir)
+
+	now := time.Now()
+	defaultCert, defaultKey := writeCertFiles(t, dir, "default", "default.example.com", now)
+	vhostCert, vhostKey := writeCertFiles(t, dir, "vhost", "live.example.com", now)
+
+	config := &Config{
+		VHosts: []*VHostConfig{
+			{Name: "live.example.com", Aliases: []string{"Alias.Example.com"}, TLSCert: vhostCert, TLSKey: vhostKey},
+			{Name: "plain.example.com"},
+		},
+	}
+
+	store, err := NewCertStore(config, defaultCert, defaultKey)
+	if err != nil {
+		t.Fatalf("NewCertStore() = %v", err)
+	}
+
+	tests := []struct {
+		serverName string
+		commonName string
+	}{
+		{"live.example.com", "live.example.com"},
+		{"LIVE.example.com", "live.example.com"}, // Server names are case insensitive
+		{"alias.example.com", "live.example.com"},
+		{"plain.example.com", "default.example.com"}, // Virtual host without certificate
+		{"unknown.example.com", "default.example.com"},
+		{"", "default.example.com"}, // Client without SNI
+	}
+
+	for _, test := range tests {
+		if commonName := commonNameOf(t, store, test.serverName); commonName != test.commonName {
+			t.Errorf("GetCertificate(%q) selected %s, want %s", test.serverName, commonName, test.commonName)
+		}
+	}
+}
+
+func TestCertStoreWithoutDefault(t *testing.T) {
+	dir := newCertDir(t)
+	defer os.RemoveAll(dir)
+
+	vhostCert, vhostKey := writeCertFiles(t, dir, "vhost", "live.example.com", time.Now())
+
+	config := &Config{
+		VHosts: []*VHostConfig{{Name: "live.example.com", TLSCert: vhostCert, TLSKey: vhostKey}},
+	}
+
+	store, err := NewCertStore(config, "", "")
+	if err != nil {
+		t.Fatalf("NewCertStore() = %v", err)
+	}
+
+	if _, err := store.GetCertificate(&tls.ClientHelloInfo{ServerName: "unknown.example.com"}); err == nil {
+		t.Error("GetCertificate() of unknown server name without default certificate succeeded")
+	}
+
+	if _, err := NewCertStore(&Config{}, "", ""); err == nil {
+		t.Error("NewCertStore() without certificates succeeded")
+	}
+
+	if _, err := NewCertStore(&Config{}, filepath.Join(dir, "missing.crt"), vhostKey); err == nil {
+		t.Error("NewCertStore() of missing certificate file succeeded")
+	}
+}
+
+func TestCertStoreReload(t *testing.T) {
+	dir := newCertDir(t)
+	defer os.RemoveAll(dir)
+
+	modTime := time.Now().Add(-time.Hour)
+	defaultCert, defaultKey := writeCertFiles(t, dir, "default", "old.example.com", modTime)
+
+	store, err := NewCertStore(&Config{}, defaultCert, defaultKey)
+	if err != nil {
+		t.Fatalf("NewCertStore() = %v", err)
+	}
+
+	// Unchanged files are not reloaded
+	store.reload()
+
+	if commonName := commonNameOf(t, store, ""); commonName != "old.example.com" {
+		t.Errorf("Certificate = %s after reload of unchanged files, want old.example.com", commonName)
+	}
+
+	// Changed files are reloaded
+	modTime = modTime.Add(time.Minute)
+	writeCertFiles(t, dir, "default", "new.example.com", modTime)
+	store.reload()
+
+	if commonName := commonNameOf(t, store, ""); commonName != "new.example.com" {
+		t.Errorf("Certificate = %s after reload, want new.example.com", commonName)
+	}
+
+	// Invalid files keep the certificate loaded
+	modTime = modTime.Add(time.Minute)
+	if err := ioutil.WriteFile(defaultCert, []byte("invalid"), 0600); err != nil {
+		t.Fatalf("WriteFile() = %v", err)
+	}
+	os.Chtimes(defaultCert, modTime, modTime)
+	store.reload()
+
+	if commonName := commonNameOf(t, store, ""); commonName != "new.example.com" {
+		t.Errorf("Certificate = %s after invalid reload, want new.example.com", commonName)
+	}
+}
@@ -0,0 +1,93 @@
+package rtmp
+
+import (
+	"github.com/frankchang0125/go-live-stream/rtmp/amf"
+)
+
+// Media of the first trackDetectionWindow milliseconds of a published stream is
+// observed to detect tracks which are not declared in onMetaData.
+const trackDetectionWindow = 5000
+
+// Tracks describes which tracks a published stream carries.
+type Tracks struct {
+	Audio    bool
+	Video    bool
+	Detected bool // Whether tracks are detected, tracks are unknown while detecting
+}
+
+// AudioOnly reports whether stream is detected to carry audio only, e.g. radio-style streams.
+func (t Tracks) AudioOnly() bool {
+	return t.Detected && t.Audio && !t.Video
+}
+
+// VideoOnly reports whether stream is detected to carry video only.
+func (t Tracks) VideoOnly() bool {
+	return t.Detected && t.Video && !t.Audio
+}
+
+// trackDetector detects tracks of a published stream from
+// hasAudio/hasVideo of onMetaData and media packets received.
+type trackDetector struct {
+	tracks         Tracks
+	metaAudio      *bool  // hasAudio of onMetaData, nil if not declared
+	metaVideo      *bool  // hasVideo of onMetaData, nil if not declared
+	started        bool   // Whether any media packet has been received
+	firstTimestamp uint32 // Timestamp of the first media packet
+}
+
+// onMetaData records tracks declared in onMetaData, reports whether tracks are changed.
+func (d *trackDetector) onMetaData(metaData amf.Object) bool {
+	if hasAudio, ok := metaData["hasAudio"].(bool); ok {
+		d.metaAudio = &hasAudio
+	}
+
+	if hasVideo, ok := metaData["hasVideo"].(bool); ok {
+		d.metaVideo = &hasVideo
+	}
+
+	// Tracks declared explicitly are trusted without waiting for detection window,
+	// unless media of undeclared tracks has been received
+	if d.metaAudio == nil || d.metaVideo == nil || d.tracks.Detected {
+		return false
+	}
+
+	d.tracks = Tracks{
+		Audio:    *d.metaAudio || d.tracks.Audio,
+		Video:    *d.metaVideo || d.tracks.Video,
+		Detected: true,
+	}
+
+	return true
+}
+
+// onPacket records media packet received, reports whether tracks are changed.
+func (d *trackDetector) onPacket(packetType int, timestamp uint32) bool {
+	if !d.started {
+		d.started = true
+		d.firstTimestamp = timestamp
+	}
+
+	changed := false
+
+	switch packetType {
+	case typeAudio:
+		changed = !d.tracks.Audio
+		d.tracks.Audio = true
+	case typeVideo:
+		changed = !d.tracks.Video
+		d.tracks.Video = true
+	}
+
+	if d.tracks.Detected {
+		// A track missing from detection shows up later
+		return changed
+	}
+
+	// Tracks are not changed until detection completes
+	if timestamp < d.firstTimestamp || timestamp-d.firstTimestamp < trackDetectionWindow {
+		return false
+	}
+
+	d.tracks.Detected = true
+	return true
+}
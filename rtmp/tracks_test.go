@@ -0,0 +1,122 @@
+package rtmp
+
+import (
+	"testing"
+
+	"github.com/frankchang0125/go-live-stream/rtmp/amf"
+)
+
+func TestTrackDetectorMetaData(t *testing.T) {
+	tests := []struct {
+		name     string
+		metaData amf.Object
+		changed  bool
+		tracks   Tracks
+	}{
+		{"audio only", amf.Object{"hasAudio": true, "hasVideo": false}, true, Tracks{Audio: true, Detected: true}},
+		{"video only", amf.Object{"hasAudio": false, "hasVideo": true}, true, Tracks{Video: true, Detected: true}},
+		{"audio and video", amf.Object{"hasAudio": true, "hasVideo": true}, true, Tracks{Audio: true, Video: true, Detected: true}},
+		{"hasVideo missing", amf.Object{"hasAudio": true}, false, Tracks{}},
+		{"not declared", amf.Object{"width": 1280.0}, false, Tracks{}},
+		{"invalid type", amf.Object{"hasAudio": "true", "hasVideo": 1.0}, false, Tracks{}},
+	}
+
+	for _, test := range tests {
+		d := &trackDetector{}
+
+		if changed := d.onMetaData(test.metaData); changed != test.changed || d.tracks != test.tracks {
+			t.Errorf("%s: onMetaData() = %v, %+v, want %v, %+v", test.name, changed, d.tracks, test.changed, test.tracks)
+		}
+	}
+}
+
+func TestTrackDetectorMetaDataAfterPackets(t *testing.T) {
+	d := &trackDetector{}
+
+	// Media of undeclared tracks received before onMetaData is kept
+	d.onPacket(typeVideo, 1000)
+
+	if !d.onMetaData(amf.Object{"hasAudio": true, "hasVideo": false}) || d.tracks.AudioOnly() {
+		t.Errorf("tracks = %+v, want audio and video", d.tracks)
+	}
+
+	// onMetaData after tracks are detected is ignored
+	if d.onMetaData(amf.Object{"hasAudio": false, "hasVideo": true}) || !d.tracks.Audio {
+		t.Errorf("onMetaData() after detection changed tracks to %+v", d.tracks)
+	}
+}
+
+func TestTrackDetectorPacketWindow(t *testing.T) {
+	d := &trackDetector{}
+
+	// Tracks are not detected within the detection window
+	for timestamp := uint32(1000); timestamp < 1000+trackDetectionWindow; timestamp += 20 {
+		if d.onPacket(typeAudio, timestamp) {
+			t.Fatalf("onPacket(%d) changed tracks within detection window", timestamp)
+		}
+	}
+
+	if d.tracks.Detected || d.tracks.AudioOnly() {
+		t.Errorf("tracks = %+v within detection window, want not detected", d.tracks)
+	}
+
+	// Timestamp before the first packet does not complete detection
+	if d.onPacket(typeAudio, 500) || d.tracks.Detected {
+		t.Errorf("tracks = %+v after earlier timestamp, want not detected", d.tracks)
+	}
+
+	if !d.onPacket(typeAudio, 1000+trackDetectionWindow) || !d.tracks.AudioOnly() {
+		t.Errorf("tracks = %+v after detection window, want audio only", d.tracks)
+	}
+
+	// Further packets of detected tracks change nothing
+	if d.onPacket(typeAudio, 1000+trackDetectionWindow+20) {
+		t.Error("onPacket() of detected track changed tracks")
+	}
+
+	// Video showing up later is added
+	if !d.onPacket(typeVideo, 1000+trackDetectionWindow+40) || d.tracks.AudioOnly() || !d.tracks.Video {
+		t.Errorf("tracks = %+v after late video, want audio and video", d.tracks)
+	}
+
+	if d.tracks.VideoOnly() {
+		t.Error("VideoOnly() = true for audio and video")
+	}
+}
+
+func TestPacketStartsPlay(t *testing.T) {
+	audioOnly := Tracks{Audio: true, Detected: true}
+	audioVideo := Tracks{Audio: true, Video: true, Detected: true}
+	detecting := Tracks{Audio: true}
+
+	keyFrame := NewPacket(typeVideo, 0, 1, nil)
+	keyFrame.keyFrame = true
+
+	sequenceHeader := NewPacket(typeVideo, 0, 1, nil)
+	sequenceHeader.keyFrame = true
+	sequenceHeader.sequenceHeader = true
+
+	audioSequenceHeader := NewPacket(typeAudio, 0, 1, nil)
+	audioSequenceHeader.sequenceHeader = true
+
+	tests := []struct {
+		name   string
+		packet *Packet
+		tracks Tracks
+		starts bool
+	}{
+		{"key frame", keyFrame, audioVideo, true},
+		{"inter frame", NewPacket(typeVideo, 0, 1, nil), audioVideo, false},
+		{"video sequence header", sequenceHeader, audioVideo, false},
+		{"audio of audio and video", NewPacket(typeAudio, 0, 1, nil), audioVideo, false},
+		{"audio of audio only", NewPacket(typeAudio, 0, 1, nil), audioOnly, true},
+		{"audio while detecting", NewPacket(typeAudio, 0, 1, nil), detecting, false},
+		{"audio sequence header of audio only", audioSequenceHeader, audioOnly, false},
+	}
+
+	for _, test := range tests {
+		if starts := test.packet.startsPlay(test.tracks); starts != test.starts {
+			t.Errorf("%s: startsPlay() = %v, want %v", test.name, starts, test.starts)
+		}
+	}
+}
@@ -0,0 +1,175 @@
+package rtmp
+
+import (
+	"errors"
+	"net"
+	"net/url"
+	"strings"
+	"sync"
+)
+
+var ErrInvalidAppName = errors.New("Invalid app name, app name must not contain \"/\"")
+
+type VHost struct {
+	config      *VHostConfig
+	apps        map[string]*AppConfig // Map<App Name>*AppConfig
+	channels    sync.Map              // Map<App/Stream Name>*Channel
+	publishAuth PublishAuthenticator  // Publishers are not authenticated if nil
+	hooks       *Hooks                // Hooks of virtual host, nil if not configured
+	appHooks    map[string]*Hooks     // Map<App Name>*Hooks
+	publishACLs map[string]*ACL       // Map<App Name>*ACL
+	playACLs    map[string]*ACL       // Map<App Name>*ACL
+}
+
+func NewVHost(config *VHostConfig) (*VHost, error) {
+	vhost := &VHost{
+		config:      config,
+		apps:        make(map[string]*AppConfig),
+		appHooks:    make(map[string]*Hooks),
+		publishACLs: make(map[string]*ACL),
+		playACLs:    make(map[string]*ACL),
+	}
+
+	for _, app := range config.Apps {
+		if app.Name == "" {
+			continue
+		}
+
+		if !isValidAppName(app.Name) {
+			return nil, ErrInvalidAppName
+		}
+
+		vhost.apps[app.Name] = app
+
+		if app.Hooks != nil {
+			vhost.appHooks[app.Name] = NewHooks(app.Hooks)
+		}
+
+		publishACL, err := NewACL(app.PublishAllow, app.PublishDeny)
+		if err != nil {
+			return nil, err
+		}
+
+		playACL, err := NewACL(app.PlayAllow, app.PlayDeny)
+		if err != nil {
+			return nil, err
+		}
+
+		vhost.publishACLs[app.Name] = publishACL
+		vhost.playACLs[app.Name] = playACL
+	}
+
+	if config.Hooks != nil {
+		vhost.hooks = NewHooks(config.Hooks)
+	}
+
+	if config.PublishKeyFile != "" {
+		auth, err := NewKeyFileAuthenticator(config.PublishKeyFile)
+		if err != nil {
+			return nil, err
+		}
+
+		vhost.publishAuth = auth
+	}
+
+	return vhost, nil
+}
+
+func (v *VHost) Name() string {
+	return v.config.Name
+}
+
+// SetPublishAuthenticator replaces the authenticator of publishers on the virtual host.
+func (v *VHost) SetPublishAuthenticator(auth PublishAuthenticator) {
+	v.publishAuth = auth
+}
+
+// isAppPermitted reports whether clients are allowed to connect to app.
+func (v *VHost) isAppPermitted(app string) bool {
+	if !isValidAppName(app) {
+		return false
+	}
+
+	if len(v.apps) == 0 {
+		return true
+	}
+
+	_, ok := v.apps[app]
+	return ok
+}
+
+// appConfig returns the configuration of app, or an empty configuration
+// if app is permitted without being configured explicitly.
+func (v *VHost) appConfig(app string) *AppConfig {
+	if config, ok := v.apps[app]; ok {
+		return config
+	}
+
+	return &AppConfig{Name: app}
+}
+
+// hooksOf returns the hooks of app, falls back to hooks of the virtual host.
+func (v *VHost) hooksOf(app string) *Hooks {
+	if hooks, ok := v.appHooks[app]; ok {
+		return hooks
+	}
+
+	return v.hooks
+}
+
+// isValidAppName reports whether app can be told apart from stream name in channel key,
+// e.g. app "a/b" with stream "c" would collide with app "a" with stream "b/c".
+func isValidAppName(app string) bool {
+	return !strings.Contains(app, "/")
+}
+
+// channelKey returns the key of a channel in the channel list,
+// streams with the same name under different applications are different channels.
+// App names never contain "/", thus keys of different channels never collide.
+func channelKey(app string, streamName string) string {
+	return app + "/" + streamName
+}
+
+// splitStreamName splits query string parameters off the stream name,
+// e.g. "mylive?key=secret".
+func splitStreamName(name string) (string, url.Values) {
+	i := strings.Index(name, "?")
+	if i < 0 {
+		return name, url.Values{}
+	}
+
+	params, err := url.ParseQuery(name[i+1:])
+	if err != nil {
+		params = url.Values{}
+	}
+
+	return name[:i], params
+}
+
+// parseVHostName returns the virtual host name requested by client,
+// ?vhost= query in app or tcUrl takes precedence over the host of tcUrl.
+func parseVHostName(tcURL string, app string) string {
+	if i := strings.Index(app, "?"); i >= 0 {
+		if query, err := url.ParseQuery(app[i+1:]); err == nil {
+			if vhost := query.Get("vhost"); vhost != "" {
+				return vhost
+			}
+		}
+	}
+
+	u, err := url.Parse(tcURL)
+	if err != nil {
+		return ""
+	}
+
+	if vhost := u.Query().Get("vhost"); vhost != "" {
+		return vhost
+	}
+
+	host := u.Host
+	if h, _, err := net.SplitHostPort(host); err == nil {
+		host = h
+	}
+
+	return host
+}
@@ -0,0 +1,56 @@
+package rtmp
+
+import (
+	"testing"
+)
+
+func TestAppNameWithSlash(t *testing.T) {
+	_, err := NewVHost(&VHostConfig{Name: "example.com", Apps: []*AppConfig{{Name: "a/b"}}})
+	if err != ErrInvalidAppName {
+		t.Errorf("NewVHost() with app \"a/b\" = %v, want %v", err, ErrInvalidAppName)
+	}
+
+	// All apps are permitted except apps containing "/"
+	vhost, err := NewVHost(&VHostConfig{Name: "example.com"})
+	if err != nil {
+		t.Fatalf("NewVHost() = %v", err)
+	}
+
+	for app, want := range map[string]bool{"live": true, "a/b": false, "/live": false} {
+		if got := vhost.isAppPermitted(app); got != want {
+			t.Errorf("isAppPermitted(%q) = %v, want %v", app, got, want)
+		}
+	}
+}
+
+func TestParseVHostName(t *testing.T) {
+	tests := []struct {
+		tcURL string
+		app   string
+		vhost string
+	}{
+		{"rtmp://a.example.com/live", "live", "a.example.com"},
+		{"rtmp://a.example.com:1935/live", "live", "a.example.com"},
+		{"rtmp://localhost/live?vhost=b.example.com", "live", "b.example.com"},
+		{"rtmp://localhost/live?vhost=b.example.com", "live?vhost=c.example.com", "c.example.com"},
+		{"rtmp://127.0.0.1:1935/live", "live", "127.0.0.1"},
+	}
+
+	for _, test := range tests {
+		if vhost := parseVHostName(test.tcURL, test.app); vhost != test.vhost {
+			t.Errorf("parseVHostName(%q, %q) = %q, want %q", test.tcURL, test.app, vhost, test.vhost)
+		}
+	}
+}
+
+func TestSplitStreamName(t *testing.T) {
+	name, params := splitStreamName("mylive?key=s3cr3t&expires=1")
+	if name != "mylive" || params.Get("key") != "s3cr3t" || params.Get("expires") != "1" {
+		t.Errorf("splitStreamName() = %q, %v", name, params)
+	}
+
+	name, params = splitStreamName("mylive")
+	if name != "mylive" || len(params) != 0 {
+		t.Errorf("splitStreamName() = %q, %v", name, params)
+	}
+}